@@ -3,8 +3,9 @@ package operations
 import (
 	"context"
 	"errors"
-	"log"
 	"os"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
 )
 
 // --- Core Logic Functions (Now Exported) ---
@@ -12,7 +13,9 @@ import (
 // EditFile performs the actual file writing.
 // Renamed to be exported.
 func EditFile(ctx context.Context, args EditFileArgs) (EditFileResponse, error) {
-	log.Println("Execute Edit File, with: ", args)
+	// File contents are never logged, only their size
+	logger := toolkit.LoggerFromContext(ctx).With("path", args.Path)
+	logger.Debug("Executing Edit File", "bytes", len(args.Content))
 
 	if args.Path == "" {
 		return EditFileResponse{
@@ -23,7 +26,7 @@ func EditFile(ctx context.Context, args EditFileArgs) (EditFileResponse, error)
 
 	err := os.WriteFile(args.Path, []byte(args.Content), 0644)
 	if err != nil {
-		log.Printf("Execute Edit File - Error: Failed to write file %s: %v", args.Path, err)
+		logger.Warn("Edit File failed to write file", toolkit.LogKeyError, err.Error())
 		return EditFileResponse{
 			Success: false,
 			Error:   err.Error(),
@@ -38,7 +41,8 @@ func EditFile(ctx context.Context, args EditFileArgs) (EditFileResponse, error)
 // ReadFile performs the actual file reading.
 // Renamed to be exported.
 func ReadFile(ctx context.Context, args ReadFileArgs) (ReadFileResponse, error) {
-	log.Println("Execute Read File, with: ", args)
+	logger := toolkit.LoggerFromContext(ctx).With("path", args.Path)
+	logger.Debug("Executing Read File")
 
 	if args.Path == "" {
 		return ReadFileResponse{
@@ -49,7 +53,7 @@ func ReadFile(ctx context.Context, args ReadFileArgs) (ReadFileResponse, error)
 
 	content, err := os.ReadFile(args.Path)
 	if err != nil {
-		log.Printf("Execute Read File - Error: Failed to read file %s: %v", args.Path, err)
+		logger.Warn("Read File failed to read file", toolkit.LogKeyError, err.Error())
 		return ReadFileResponse{
 			Success: false,
 			Error:   err.Error(),
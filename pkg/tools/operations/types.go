@@ -25,4 +25,4 @@ type ReadFileResponse struct {
 }
 
 // ReadFileInfo and EditFileInfo variables removed as they are no longer needed.
-// Schema is generated dynamically by the toolkit.NewChild builder.
+// Schema is generated dynamically by the toolkit.NewTypedChild builder.
@@ -2,7 +2,8 @@ package response
 
 import (
 	"context"
-	"log"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
 )
 
 // --- Core Logic Functions (Now Exported) ---
@@ -10,13 +11,13 @@ import (
 // AIThinking performs the actual logging.
 // Renamed to be exported.
 func LogThinking(ctx context.Context, args ModelThinkingArgs) (ModelThinking, error) {
-	log.Println("Executing Model Thinking")
-	// Context is available but unused in this simple logging function
+	// Logging the thinking is the purpose of this tool, so it is logged at info level
+	logger := toolkit.LoggerFromContext(ctx)
 	if args.Thinking == "" {
-		log.Println("Model Thinking Warning: Received empty thinking string.")
+		logger.Warn("Model Thinking received an empty thinking string")
 		return ModelThinking{Success: true}, nil
 	}
-	log.Printf("--- MODEL THINKING START ---\n%s\n--- MODEL THINKING END ---", args.Thinking)
+	logger.Info("Model Thinking", "thinking", args.Thinking)
 	return ModelThinking{
 		Success: true,
 	}, nil
@@ -24,11 +25,11 @@ func LogThinking(ctx context.Context, args ModelThinkingArgs) (ModelThinking, er
 
 // LogResponse logs that a response call was made.
 func LogResponse(ctx context.Context, args ModelResponseArgs) (ModelResponse, error) {
-	log.Println("Executing Model Response.")
+	logger := toolkit.LoggerFromContext(ctx)
 	if args.Response == "" {
-		log.Println("Model Response Warning: Received empty user response string.")
+		logger.Warn("Model Response received an empty user response string")
 		return ModelResponse{Success: true}, nil
 	}
-	log.Printf("--- MODEL RESPONSE START ---\n%s\n--- MODEL RESPONSE END ---", args.Response)
+	logger.Info("Model Response", "response", args.Response)
 	return ModelResponse{Success: true}, nil
 }
@@ -5,7 +5,7 @@ package response
 
 // --- Predefined Child Tool Information ---
 // Removed LogThinkingInfo and LogResponseInfo variables.
-// Schema generation handled by toolkit.NewChild.
+// Schema generation handled by toolkit.NewTypedChild.
 
 // --- Argument Structs for Child Tools ---
 
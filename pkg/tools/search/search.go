@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
 )
 
 // --- Core Logic Functions (Exported) ---
@@ -12,7 +13,7 @@ import (
 // SearchWeb provides a simple mock implementation for web search.
 // It returns fake results for demonstration purposes.
 func SearchWeb(ctx context.Context, args SearchWebArgs) (SearchWebResponse, error) {
-	log.Println("Executing Search Web with query:", args.Query)
+	toolkit.LoggerFromContext(ctx).Debug("Executing Search Web", "query", args.Query)
 
 	if args.Query == "" {
 		return SearchWebResponse{
@@ -34,7 +35,7 @@ func SearchWeb(ctx context.Context, args SearchWebArgs) (SearchWebResponse, erro
 // FetchURLContent provides a simple mock implementation for fetching URL content.
 // It returns fake HTML content for demonstration purposes.
 func FetchURLContent(ctx context.Context, args FetchURLArgs) (FetchURLResponse, error) {
-	log.Println("Executing Fetch URL Content for URL:", args.URL)
+	toolkit.LoggerFromContext(ctx).Debug("Executing Fetch URL Content", "url", args.URL)
 
 	if args.URL == "" {
 		return FetchURLResponse{
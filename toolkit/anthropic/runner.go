@@ -0,0 +1,305 @@
+// Package anthropic runs toolkits in a conversation loop with Anthropic's Claude Messages API.
+// The Runner registers a toolkit as Claude tools, executes every tool_use block the model
+// emits, returns all tool results of a turn in a single user message, and records a
+// typed transcript of the conversation.
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sdk "github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+)
+
+// DefaultMaxTurns is the number of model requests a Runner makes before it gives up.
+const DefaultMaxTurns = 10
+
+// StopReason explains why a Runner ended a conversation.
+type StopReason string
+
+const (
+	// StopReasonCompleted means the model answered without requesting any tool.
+	StopReasonCompleted StopReason = "completed"
+	// StopReasonMaxTurns means the turn limit was reached while the model still requested tools.
+	StopReasonMaxTurns StopReason = "max_turns"
+	// StopReasonStopCondition means the configured stop condition ended the conversation.
+	StopReasonStopCondition StopReason = "stop_condition"
+)
+
+// --- Transcript ---
+
+// Transcript is the record of a conversation run by a Runner.
+type Transcript struct {
+	Messages   []sdk.MessageParam // Full conversation history, ready to continue with RunMessages
+	Turns      []Turn             // One entry per model request
+	StopReason StopReason         // Why the conversation ended
+	Usage      Usage              // Token usage summed over all turns
+}
+
+// Turn is a single model request and the tool calls it triggered.
+type Turn struct {
+	Index     int          // 1-based turn number
+	Response  *sdk.Message // The model response
+	ToolCalls []ToolCall   // Tool calls executed for this turn, in the order the model emitted them
+}
+
+// ToolCall is the execution record of a single tool_use block.
+type ToolCall struct {
+	ID       string          // The tool_use block ID
+	Name     string          // The tool name chosen by the model
+	Input    json.RawMessage // The tool input chosen by the model
+	Result   interface{}     // The toolkit result (ToolKitResponse, or a ChildResponse in flat mode)
+	Content  string          // The tool_result text returned to the model
+	IsError  bool            // Whether the tool_result was flagged as an error: the call or any of its children failed
+	Duration time.Duration   // Time spent executing the tool
+}
+
+// Usage sums the token usage of the model requests of a conversation.
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// FinalText returns the text blocks of the last model response, joined by newlines.
+func (t *Transcript) FinalText() string {
+	if len(t.Turns) == 0 {
+		return ""
+	}
+	text := ""
+	for _, block := range t.Turns[len(t.Turns)-1].Response.Content {
+		if block.Type != sdk.ContentBlockTypeText {
+			continue
+		}
+		if text != "" {
+			text += "\n"
+		}
+		text += block.Text
+	}
+	return text
+}
+
+// --- Runner ---
+
+// Hooks are optional callbacks invoked while a Runner drives a conversation.
+// A hook returning an error aborts the run with that error.
+type Hooks struct {
+	// BeforeRequest is called before every model request and may adjust its params.
+	BeforeRequest func(ctx context.Context, turn int, params *sdk.MessageNewParams) error
+	// AfterResponse is called with every model response.
+	AfterResponse func(ctx context.Context, turn int, response *sdk.Message) error
+	// AfterToolCall is called after every executed tool call.
+	AfterToolCall func(ctx context.Context, turn int, call ToolCall) error
+}
+
+// Runner drives a conversation between Claude and a toolkit.
+// A Runner is safe for concurrent use; every Run has its own history.
+type Runner struct {
+	client    *sdk.Client
+	toolkit   *toolkit.Toolkit
+	params    sdk.MessageNewParams
+	maxTurns  int
+	stop      func(turn Turn) bool
+	hooks     Hooks
+	flatTools bool
+}
+
+// RunnerOption configures a Runner created with NewRunner.
+type RunnerOption func(*Runner)
+
+// WithMaxTurns sets the maximum number of model requests per run. The default is DefaultMaxTurns,
+// which is also used for values of zero or less.
+func WithMaxTurns(n int) RunnerOption {
+	return func(r *Runner) {
+		if n <= 0 {
+			n = DefaultMaxTurns
+		}
+		r.maxTurns = n
+	}
+}
+
+// WithStopCondition ends the conversation after any turn for which stop returns true,
+// even if the model requested tools in that turn. The tool results of that turn are
+// still part of the transcript.
+func WithStopCondition(stop func(turn Turn) bool) RunnerOption {
+	return func(r *Runner) {
+		r.stop = stop
+	}
+}
+
+// WithHooks sets the callbacks invoked during a run.
+func WithHooks(hooks Hooks) RunnerOption {
+	return func(r *Runner) {
+		r.hooks = hooks
+	}
+}
+
+// WithFlatTools registers every child as its own tool (see toolkit.Toolkit.GetFlatTools)
+// instead of the single hierarchical toolkit tool.
+func WithFlatTools() RunnerOption {
+	return func(r *Runner) {
+		r.flatTools = true
+	}
+}
+
+// NewRunner creates a Runner for the given client and toolkit. The params are used as
+// the base of every model request (model, max tokens, system prompt, temperature, ...);
+// their Messages and Tools are replaced by the conversation history and the toolkit tools.
+//
+// Example:
+//
+//	runner := anthropic.NewRunner(client, myToolkit, sdk.MessageNewParams{
+//	    Model:     sdk.F(sdk.ModelClaude3_7Sonnet20250219),
+//	    MaxTokens: sdk.Int(1024),
+//	}, anthropic.WithMaxTurns(5))
+//	transcript, err := runner.Run(ctx, "Read test.txt and summarize it")
+//	if err != nil { ... }
+//	fmt.Println(transcript.FinalText())
+func NewRunner(client *sdk.Client, tk *toolkit.Toolkit, params sdk.MessageNewParams, opts ...RunnerOption) *Runner {
+	r := &Runner{
+		client:   client,
+		toolkit:  tk,
+		params:   params,
+		maxTurns: DefaultMaxTurns,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// Run starts a conversation with a single user prompt.
+func (r *Runner) Run(ctx context.Context, prompt string) (*Transcript, error) {
+	return r.RunMessages(ctx, []sdk.MessageParam{sdk.NewUserMessage(sdk.NewTextBlock(prompt))})
+}
+
+// RunMessages continues a conversation from the given history, which must end with a
+// user message. It returns the transcript so far together with any error, so callers
+// can inspect partial conversations.
+func (r *Runner) RunMessages(ctx context.Context, messages []sdk.MessageParam) (*Transcript, error) {
+	transcript := &Transcript{Messages: append([]sdk.MessageParam(nil), messages...)}
+
+	tools, err := r.tools()
+	if err != nil {
+		return transcript, err
+	}
+
+	for index := 1; ; index++ {
+		if index > r.maxTurns {
+			transcript.StopReason = StopReasonMaxTurns
+			return transcript, nil
+		}
+
+		params := r.params
+		params.Messages = sdk.F(transcript.Messages)
+		params.Tools = sdk.F(tools)
+		if r.hooks.BeforeRequest != nil {
+			if err := r.hooks.BeforeRequest(ctx, index, &params); err != nil {
+				return transcript, err
+			}
+		}
+
+		response, err := r.client.Messages.New(ctx, params)
+		if err != nil {
+			return transcript, fmt.Errorf("error calling Claude API (turn %d): %w", index, err)
+		}
+		transcript.Messages = append(transcript.Messages, response.ToParam())
+		transcript.Usage.InputTokens += response.Usage.InputTokens
+		transcript.Usage.OutputTokens += response.Usage.OutputTokens
+		if r.hooks.AfterResponse != nil {
+			if err := r.hooks.AfterResponse(ctx, index, response); err != nil {
+				return transcript, err
+			}
+		}
+
+		turn := Turn{Index: index, Response: response}
+		var results []sdk.ContentBlockParamUnion
+		for _, block := range response.Content {
+			toolUse, ok := block.AsUnion().(sdk.ToolUseBlock)
+			if !ok {
+				continue
+			}
+			call := r.executeTool(ctx, toolUse)
+			turn.ToolCalls = append(turn.ToolCalls, call)
+			results = append(results, sdk.NewToolResultBlock(call.ID, call.Content, call.IsError))
+			if r.hooks.AfterToolCall != nil {
+				if err := r.hooks.AfterToolCall(ctx, index, call); err != nil {
+					transcript.Turns = append(transcript.Turns, turn)
+					return transcript, err
+				}
+			}
+		}
+		transcript.Turns = append(transcript.Turns, turn)
+
+		if len(results) == 0 {
+			transcript.StopReason = StopReasonCompleted
+			return transcript, nil
+		}
+		// All tool results of a turn go back in one user message
+		transcript.Messages = append(transcript.Messages, sdk.NewUserMessage(results...))
+
+		if r.stop != nil && r.stop(turn) {
+			transcript.StopReason = StopReasonStopCondition
+			return transcript, nil
+		}
+	}
+}
+
+// tools builds the Claude tool definitions of the toolkit.
+func (r *Runner) tools() ([]sdk.ToolUnionUnionParam, error) {
+	if r.flatTools {
+		flat := r.toolkit.GetFlatTools()
+		tools := make([]sdk.ToolUnionUnionParam, 0, len(flat))
+		for _, tool := range flat {
+			tools = append(tools, sdk.ToolParam{
+				Name:        sdk.F(tool.Name),
+				Description: sdk.F(tool.Description),
+				InputSchema: sdk.F(tool.InputSchema),
+			})
+		}
+		return tools, nil
+	}
+
+	schema, err := r.toolkit.GetToolkitSchema("anthropic")
+	if err != nil {
+		return nil, fmt.Errorf("error generating toolkit schema: %w", err)
+	}
+	return []sdk.ToolUnionUnionParam{sdk.ToolParam{
+		Name:        sdk.F(r.toolkit.GetToolkitName()),
+		Description: sdk.F(r.toolkit.GetToolkitDescription()),
+		InputSchema: sdk.F(schema),
+	}}, nil
+}
+
+// executeTool runs a single tool_use block against the toolkit.
+func (r *Runner) executeTool(ctx context.Context, toolUse sdk.ToolUseBlock) ToolCall {
+	call := ToolCall{ID: toolUse.ID, Name: toolUse.Name, Input: toolUse.Input}
+	start := time.Now()
+	ctx = toolkit.ContextWithCallID(ctx, toolUse.ID) // The toolkit logs the call under the tool_use ID
+
+	var failed bool
+	switch {
+	case !r.flatTools && toolUse.Name == r.toolkit.GetToolkitName():
+		resp, err := r.toolkit.HandleToolKit(ctx, toolUse.Input)
+		// A failed child fails the whole tool_result, as in flat mode, so the model notices it
+		call.Result, failed = resp, err != nil || resp.HasErrors()
+	case r.flatTools:
+		resp, err := r.toolkit.HandleFlatTool(ctx, toolUse.Name, toolUse.Input)
+		call.Result, failed = resp, err != nil || resp.Failed()
+	default:
+		call.Result, failed = toolkit.NewError(toolkit.CodeToolNotFound, fmt.Sprintf("Tool '%s' not registered", toolUse.Name)), true
+	}
+
+	content, err := json.Marshal(call.Result)
+	if err != nil {
+		content, failed = []byte(fmt.Sprintf("Error marshaling tool result: %v", err)), true
+	}
+	call.Content, call.IsError = string(content), failed
+	call.Duration = time.Since(start)
+	return call
+}
@@ -6,8 +6,17 @@ package toolkit
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/invopop/jsonschema"
 )
 
 // --- Child Builder ---
@@ -16,10 +25,13 @@ import (
 // It's created by NewChild and wraps the user-provided handler function to handle
 // argument unmarshaling, validation, and interface conformance automatically.
 type internalChild[ArgsT any] struct {
+	childConfig
 	name        string
 	description string
 	handlerFunc func(ctx context.Context, args ArgsT) (interface{}, error)
-	schema      interface{} // Cached schema generated by GenerateSchema
+	schema      interface{}               // Cached schema generated by GenerateSchema
+	patterns    map[string]*regexp.Regexp // Regular expressions of schema, compiled once
+	schemaErr   error                     // Why schema cannot validate arguments, such as an invalid pattern
 }
 
 // NewChild creates a new Child tool definition using a type-safe builder pattern.
@@ -30,6 +42,7 @@ type internalChild[ArgsT any] struct {
 //   - name: The unique name for this child tool within its parent (must be unique within a parent)
 //   - description: A human-readable description of what the tool does (used for documentation)
 //   - handlerFunc: The function that implements the tool's core logic
+//   - opts: Optional settings such as WithChildTimeout
 //
 // The handlerFunc signature must be func(ctx context.Context, args ArgsT) (interface{}, error),
 // where ArgsT is a struct type defining the expected arguments. The schema for ArgsT
@@ -60,16 +73,30 @@ type internalChild[ArgsT any] struct {
 //
 // Returns:
 //   - A fully configured Child instance ready to be added to a Parent
-func NewChild[ArgsT any](name, description string, handlerFunc func(ctx context.Context, args ArgsT) (interface{}, error)) Child {
+func NewChild[ArgsT any](name, description string, handlerFunc func(ctx context.Context, args ArgsT) (interface{}, error), opts ...ChildOption) Child {
 	// Use the centralized GenerateSchema helper from types.go
 	schema := GenerateSchema[ArgsT]()
 
-	return &internalChild[ArgsT]{
+	c := &internalChild[ArgsT]{
 		name:        name,
 		description: description,
 		handlerFunc: handlerFunc,
 		schema:      schema, // Store the generated schema
 	}
+	if s, ok := schema.(*jsonschema.Schema); ok {
+		c.patterns, c.schemaErr = compilePatterns(s)
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&c.childConfig)
+		}
+	}
+	return c
+}
+
+// enforcesDeadline reports that Handle applies the child timeout to each attempt itself.
+func (c *internalChild[ArgsT]) enforcesDeadline() bool {
+	return true
 }
 
 // GetName implements the Child interface by returning the tool's name.
@@ -89,37 +116,123 @@ func (c *internalChild[ArgsT]) GetInputSchema() interface{} {
 
 // Handle implements the Child interface by unmarshaling the args and calling the handler.
 // It performs several important functions:
+//   - Validate the raw JSON arguments against the cached input schema, reporting every
+//     violation in a single "invalid_arguments" error before the handler is called; a schema
+//     with an invalid pattern fails every call with an "invalid_schema" error
 //   - Unmarshal the raw JSON arguments into the strongly-typed ArgsT structure
 //   - Handle empty/null argument cases gracefully
 //   - Call the user-provided handler function with the typed arguments
 //   - Convert native Go errors to structured ToolKitError instances
 //   - Propagate context to the handler for cancellation/timeout support
+//   - Enforce the child timeout (WithChildTimeout or RequestOptions.ChildTimeout),
+//     returning a "timeout" error even if the handler ignores its context
 func (c *internalChild[ArgsT]) Handle(ctx context.Context, args json.RawMessage) (interface{}, error) {
 	var typedArgs ArgsT
 
-	// Handle cases where ArgsT might be an empty struct (no args expected)
-	if string(args) == "{}" || string(args) == "" || string(args) == "null" {
-		// If ArgsT is not strictly struct{}, unmarshal might fail depending on type.
-		// We attempt unmarshal anyway, assuming default zero values are okay for empty input.
-		// If ArgsT MUST NOT be empty, the handler func should validate.
+	// Empty or null arguments are treated as an empty object, so required
+	// properties are still reported by the schema validation below.
+	args = normalizeArgs(args)
+
+	if c.schemaErr != nil {
+		return nil, NewError(CodeInvalidSchema, fmt.Sprintf("Input schema of tool '%s' is invalid: %v", c.name, c.schemaErr), WithCause(c.schemaErr))
+	}
+	if schema, ok := c.schema.(*jsonschema.Schema); ok {
+		if violations := validateArgs(schema, c.patterns, args); len(violations) > 0 {
+			return nil, NewError(CodeInvalidArguments,
+				fmt.Sprintf("Arguments for tool '%s' do not match its input schema: %s", c.name, formatViolations(violations)),
+				WithViolations(violations),
+				WithHint("Correct every listed violation and call the tool again."))
+		}
 	}
 
 	if err := json.Unmarshal(args, &typedArgs); err != nil {
-		return nil, NewError("invalid_arguments", fmt.Sprintf("Error unmarshaling arguments for tool '%s': %v. Input: %s", c.name, err, string(args)))
+		return nil, NewError(CodeInvalidArguments, fmt.Sprintf("Error unmarshaling arguments for tool '%s': %v", c.name, err))
 	}
 
-	// Pass the received context down to the handler
-	result, err := c.handlerFunc(ctx, typedArgs)
-	if err != nil {
-		// Check if the error is already a ToolKitError, otherwise wrap it
-		if _, ok := err.(ToolKitError); ok {
-			return nil, err
-		} else {
-			return nil, NewError("handler_execution_error", fmt.Sprintf("Error executing tool '%s': %v", c.name, err))
-		}
+	// Pass the received context down to the handler, bounded by the child timeout
+	timeout := overrideTimeout(c.timeout, requestOptionsFrom(ctx).ChildTimeout)
+	attempt := func(ctx context.Context) (interface{}, error) {
+		return callWithTimeout(ctx, timeout, c.name, func(ctx context.Context) (interface{}, error) {
+			result, err := c.handlerFunc(ctx, typedArgs)
+			if err != nil {
+				// Check if the error is already a ToolKitError, otherwise wrap it, keeping
+				// the original error reachable through errors.Is and errors.As
+				var tkErr ToolKitError
+				if errors.As(err, &tkErr) {
+					return nil, err
+				}
+				return nil, NewError(CodeHandlerExecutionError, fmt.Sprintf("Error executing tool '%s': %v", c.name, err), WithCause(err))
+			}
+			return result, nil
+		})
+	}
+	if c.retry != nil {
+		return retry(ctx, *c.retry, c.name, attempt)
+	}
+	return attempt(ctx)
+}
+
+// Rollback implements the Rollbacker interface by calling the function set with WithRollback.
+// A panic in that function is recovered and returned as a "handler_panic" error.
+func (c *internalChild[ArgsT]) Rollback(ctx context.Context, args json.RawMessage, result interface{}) error {
+	if c.rollback == nil {
+		return nil
+	}
+	_, err := recoverPanic(ctx, c.name, func(ctx context.Context) (interface{}, error) {
+		return nil, c.rollback(ctx, args, result)
+	})
+	return err
+}
+
+// hasRollback reports whether a rollback function was set with WithRollback.
+func (c *internalChild[ArgsT]) hasRollback() bool {
+	return c.rollback != nil
+}
+
+// typedChild is a Child created by NewTypedChild. It adds the output schema generated
+// from the result type to an internalChild.
+type typedChild[ArgsT, ResultT any] struct {
+	*internalChild[ArgsT]
+	outputSchema interface{} // Cached schema of ResultT generated by GenerateSchema
+}
+
+// NewTypedChild creates a Child like NewChild, for handlers returning a concrete result type.
+// The output schema of ResultT is generated once and exposed through GetOutputSchema
+// (see TypedChild), so the model knows the shape of the results before calling the tool.
+//
+// Parameters:
+//   - name: The unique name for this child tool within its parent (must be unique within a parent)
+//   - description: A human-readable description of what the tool does (used for documentation)
+//   - handlerFunc: The function that implements the tool's core logic
+//   - opts: Optional settings such as WithChildTimeout
+//
+// Example:
+//
+//	readFileTool := toolkit.NewTypedChild("read_file", "Reads content from a file", operations.ReadFile)
+//
+// Returns:
+//   - A fully configured Child instance, implementing TypedChild, ready to be added to a Parent
+func NewTypedChild[ArgsT, ResultT any](name, description string, handlerFunc func(ctx context.Context, args ArgsT) (ResultT, error), opts ...ChildOption) Child {
+	handler := func(ctx context.Context, args ArgsT) (interface{}, error) {
+		return handlerFunc(ctx, args)
 	}
+	return &typedChild[ArgsT, ResultT]{
+		internalChild: NewChild(name, description, handler, opts...).(*internalChild[ArgsT]),
+		outputSchema:  GenerateSchema[ResultT](),
+	}
+}
+
+// GetOutputSchema implements the TypedChild interface by returning the cached output schema.
+func (c *typedChild[ArgsT, ResultT]) GetOutputSchema() interface{} {
+	return c.outputSchema
+}
 
-	return result, nil
+// OutputSchemaOf returns the output schema of a child implementing TypedChild, or nil.
+func OutputSchemaOf(child Child) interface{} {
+	if typed, ok := child.(TypedChild); ok {
+		return typed.GetOutputSchema()
+	}
+	return nil
 }
 
 // --- Parent Builder ---
@@ -128,8 +241,10 @@ func (c *internalChild[ArgsT]) Handle(ctx context.Context, args json.RawMessage)
 // It's created by NewParent and manages a collection of Child tools, handling
 // tool lookup, execution orchestration, and response aggregation.
 type internalParent struct {
+	parentConfig
 	name        string
 	description string
+	mu          sync.RWMutex     // Guards children, which may change while requests are served
 	children    map[string]Child // Map of child tools by name for efficient lookup
 }
 
@@ -162,22 +277,53 @@ type internalParent struct {
 // Returns:
 //   - A fully configured Parent instance ready to be added to a Toolkit
 func NewParent(name, description string, children ...Child) Parent {
-	childMap := make(map[string]Child, len(children))
+	return NewParentWithOptions(name, description, nil, children...)
+}
+
+// NewParentWithOptions creates a new Parent like NewParent, and additionally applies
+// the provided options (such as WithParentTimeout) to it.
+//
+// Example:
+//
+//	fileOpsParent := toolkit.NewParentWithOptions(
+//	    "file_operations",
+//	    "Tools for file system operations",
+//	    []toolkit.ParentOption{toolkit.WithParentTimeout(10 * time.Second)},
+//	    readFileTool, writeFileTool,
+//	)
+func NewParentWithOptions(name, description string, opts []ParentOption, children ...Child) Parent {
+	p := &internalParent{
+		name:        name,
+		description: description,
+		children:    make(map[string]Child, len(children)),
+	}
+	// Options are applied first so registration warnings use the configured logger
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&p.parentConfig)
+		}
+	}
+
+	logger := p.parentLogger().With(LogKeyParent, name)
 	for _, child := range children {
 		if child == nil {
-			log.Printf("Warning: nil child provided to NewParent '%s', skipping.", name)
+			logger.Warn("Nil child provided to NewParent, skipping")
 			continue
 		}
-		if _, exists := childMap[child.GetName()]; exists {
-			log.Printf("Warning: Duplicate child name '%s' detected in parent '%s'. Overwriting.", child.GetName(), name)
+		if _, exists := p.children[child.GetName()]; exists {
+			logger.Warn("Duplicate child name detected in NewParent, overwriting", LogKeyChild, child.GetName())
 		}
-		childMap[child.GetName()] = child
+		p.children[child.GetName()] = child
 	}
-	return &internalParent{
-		name:        name,
-		description: description,
-		children:    childMap,
+	return p
+}
+
+// parentLogger returns the logger set with WithParentLogger, or slog.Default().
+func (p *internalParent) parentLogger() *slog.Logger {
+	if p.logger != nil {
+		return p.logger
 	}
+	return slog.Default()
 }
 
 // GetName implements the Parent interface by returning the parent's name.
@@ -190,13 +336,49 @@ func (p *internalParent) GetDescription() string {
 	return p.description
 }
 
-// GetChildren implements the Parent interface by returning the map of child tools.
-// This map is used for tool discovery and schema generation.
+// GetChildren implements the Parent interface by returning a snapshot of the child tools.
+// This map is used for tool discovery and schema generation; changing it does not affect
+// the parent (see AddChild and RemoveChild).
 func (p *internalParent) GetChildren() map[string]Child {
-	// Note: This returns a direct reference to the internal map.
-	// In the current design, this is acceptable as the map is only used for
-	// read operations after parent creation.
-	return p.children
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return maps.Clone(p.children)
+}
+
+// child returns the registered child with the given name.
+func (p *internalParent) child(name string) (Child, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	child, ok := p.children[name]
+	return child, ok
+}
+
+// childNames returns the names of the registered children in name order.
+func (p *internalParent) childNames() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return sortedNames(p.children)
+}
+
+// AddChild implements the MutableParent interface by registering child, replacing any
+// child with the same name. Requests that already looked up a child keep using it.
+func (p *internalParent) AddChild(child Child) {
+	if child == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.children[child.GetName()] = child
+}
+
+// RemoveChild implements the MutableParent interface by unregistering the named child.
+// Running invocations of the child complete normally; later requests get child_not_found.
+func (p *internalParent) RemoveChild(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.children[name]
+	delete(p.children, name)
+	return ok
 }
 
 // HandleChildren implements the Parent interface by executing the requested child tools.
@@ -206,45 +388,154 @@ func (p *internalParent) GetChildren() map[string]Child {
 //   - Capturing successful results and errors in a consistent response format
 //   - Ensuring all requests get a response, even if errors occur
 //
-// Each child is executed sequentially in the order of the requests, with the
-// context passed down to each child's Handle method.
+// By default each child is executed sequentially in the order of the requests.
+// When the owning Toolkit runs in ExecutionConcurrent mode, children are executed
+// concurrently (bounded by the toolkit's concurrency limit) and the responses are
+// still returned in request order. The context is passed down to each child's Handle method.
+//
+// If the parent timeout (WithParentTimeout or RequestOptions.ParentTimeout) expires,
+// children that have not finished report a "timeout" error while finished children
+// keep their results.
+//
+// Under PolicyStopOnFirstError and PolicyAllOrNothing (see WithExecutionPolicy), children
+// are no longer started once a child of the request has failed; they report a "skipped" error.
+//
+// Progress events (see ContextWithEventHandler) are emitted as each child starts and finishes.
 func (p *internalParent) HandleChildren(ctx context.Context, childRequests []ToolKitChild) ParentResponse {
+	if timeout := overrideTimeout(p.timeout, requestOptionsFrom(ctx).ParentTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	resp := ParentResponse{
 		Name:            p.name,
 		ChildsResponses: make([]ChildResponse, 0, len(childRequests)),
 	}
 
-	for _, req := range childRequests {
-		child, ok := p.children[req.Name]
-		if !ok {
-			log.Printf("Parent '%s': Child '%s' not found.", p.name, req.Name)
-			cr := ChildResponse{
-				Name:     req.Name,
-				Response: NewError("child_not_found", fmt.Sprintf("Child tool '%s' not found within parent '%s'", req.Name, p.name)),
-			}
-			resp.AddResponse(cr)
-			continue
+	exec := executionFrom(ctx)
+	if !exec.concurrent() {
+		for i, req := range childRequests {
+			resp.AddResponse(p.runChild(ctx, i, req))
 		}
+		return resp
+	}
 
-		// Execute the child's handler, passing the context
-		result, err := child.Handle(ctx, req.Args)
-
-		// Create child response based on outcome
-		var childResp ChildResponse
-		if err != nil {
-			log.Printf("Parent '%s', Child '%s': Execution error: %v", p.name, req.Name, err)
-			childResp = ChildResponse{
-				Name:     req.Name,
-				Response: err, // Pass the ToolKitError (or wrapped error) directly
+	resp.ChildsResponses = resp.ChildsResponses[:len(childRequests)]
+	var wg sync.WaitGroup
+	for i, req := range childRequests {
+		wg.Add(1)
+		go func(i int, req ToolKitChild) {
+			defer wg.Done()
+			if err := exec.acquire(ctx); err != nil {
+				resp.ChildsResponses[i] = NewChildError(req.Name, contextError(req.Name, err))
+				exec.fail(p.name, req.Name)
+				EmitEvent(ctx, childFinishedEvent(p.name, eventChildIndex(ctx, i), resp.ChildsResponses[i]))
+				return
 			}
-		} else {
-			childResp = ChildResponse{
-				Name:     req.Name,
-				Response: result,
-			}
-		}
-		resp.AddResponse(childResp)
+			ctx, slot := withSlotHold(ctx, exec.release)
+			defer slot.done()
+			resp.ChildsResponses[i] = p.runChild(ctx, i, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	return resp
+}
+
+// runChild executes a single child request, applies the execution policy and reports its
+// progress events. index is the position of the child within the parent request.
+func (p *internalParent) runChild(ctx context.Context, index int, req ToolKitChild) ChildResponse {
+	if override := eventChildIndex(ctx, -1); override >= 0 {
+		index, ctx = override, withEventChild(ctx, -1) // Don't leak the index into nested toolkits
+	}
+	exec := executionFrom(ctx)
+	if exec.stopped() {
+		resp := exec.skippedResponse(req.Name)
+		EmitEvent(ctx, childFinishedEvent(p.name, index, resp))
+		return resp
+	}
+
+	EmitEvent(ctx, Event{Type: EventChildStarted, Parent: p.name, Child: req.Name, ChildIndex: index})
+	start := time.Now()
+	spanCtx, span := startChildSpan(ctx, p.name, req)
+	// The child is looked up once, so a concurrent AddChild or RemoveChild cannot split
+	// the execution and a later rollback between two implementations
+	child, _ := p.child(req.Name)
+	resp := p.handleChild(spanCtx, req, child)
+	duration := time.Since(start)
+
+	// Result sizes cost a marshal, so they are only computed when they are recorded
+	resultSize := -1
+	if exec.metrics != nil || span.IsRecording() {
+		resultSize = responseSize(resp)
+	}
+	endChildSpan(span, resp, resultSize)
+	observeChild(ctx, ChildObservation{
+		Parent:     p.name,
+		Child:      req.Name,
+		Duration:   duration,
+		ArgsSize:   len(req.Args),
+		ResultSize: max(resultSize, 0),
+		ErrorCode:  errorCode(resp),
+	})
+	exec.observe(ctx, p.name, index, req, resp, child)
+	EmitEvent(ctx, childFinishedEvent(p.name, index, resp))
+	return resp
+}
+
+// handleChild executes a single child request, converting the outcome into a ChildResponse.
+// A nil child, for names that are not registered, produces a child_not_found error response.
+func (p *internalParent) handleChild(ctx context.Context, req ToolKitChild, child Child) ChildResponse {
+	logger := p.parentLogger()
+	if _, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		logger = LoggerFromContext(ctx)
 	}
+	logger = logger.With(LogKeyParent, p.name, LogKeyChild, req.Name)
 
+	if child == nil {
+		available := p.childNames()
+		err := NewError(CodeChildNotFound, fmt.Sprintf("Child tool '%s' not found within parent '%s'", req.Name, p.name),
+			WithDetails(map[string]any{"available": available}),
+			WithHint(fmt.Sprintf("Use one of the children of '%s': %s.", p.name, strings.Join(available, ", "))))
+		logger.Warn("Requested child not found", errorAttrs(err)...)
+		return NewChildError(req.Name, err)
+	}
+
+	// Handlers log through LoggerFromContext with the same attributes
+	ctx = withLogger(ctx, logger)
+	// Arguments may hold file contents or credentials, so only their size is logged
+	logger.Debug("Child started", LogKeyArgsBytes, len(req.Args))
+	start := time.Now()
+
+	// Execute the child's handler through the toolkit, parent and child middleware, passing
+	// the context. The deadline is enforced around custom Child implementations as well, so
+	// those that ignore their context cannot block the batch; children created with NewChild
+	// enforce it themselves. The request child timeout bounds each call of the child, so
+	// every attempt of RetryMiddleware gets the full timeout. Panics in middleware are
+	// recovered like panics in handlers.
+	handler := chainMiddleware(func(ctx context.Context, call ChildCall) (interface{}, error) {
+		if enforcesDeadline(child) {
+			return child.Handle(ctx, call.Args)
+		}
+		return callWithTimeout(ctx, requestOptionsFrom(ctx).ChildTimeout, req.Name, func(ctx context.Context) (interface{}, error) {
+			return child.Handle(ctx, call.Args)
+		})
+	}, executionFrom(ctx).mw, p.middleware, childMiddleware(child))
+	// Retries record their attempt count so it can be reported with the response
+	var attempts atomic.Int32
+	call := ChildCall{Parent: p.name, Child: req.Name, Args: req.Args}
+	result, err := recoverPanic(withAttempts(ctx, &attempts), req.Name, func(ctx context.Context) (interface{}, error) {
+		return handler(ctx, call)
+	})
+	var resp ChildResponse
+	if err != nil {
+		logger.Warn("Child failed", append([]interface{}{LogKeyDuration, time.Since(start)}, errorAttrs(err)...)...)
+		resp = NewChildError(req.Name, err)
+	} else {
+		logger.Debug("Child finished", LogKeyDuration, time.Since(start))
+		resp = NewChildResult(req.Name, result)
+	}
+	resp.Attempts = int(attempts.Load())
 	return resp
 }
@@ -31,6 +31,7 @@ type Parent interface {
 
 	// GetChildren returns a map of the child tools managed by this parent,
 	// keyed by their unique names. These names are used for lookup during execution.
+	// Parents whose children change at runtime must return a snapshot, not their live map.
 	GetChildren() map[string]Child
 
 	// HandleChildren processes a list of child tool execution requests.
@@ -67,3 +68,29 @@ type Child interface {
 	// and should return ToolKitError instances for structured error handling.
 	Handle(ctx context.Context, args json.RawMessage) (interface{}, error)
 }
+
+// TypedChild is implemented by children that declare the shape of their results.
+// The output schema is optional: toolkits include it in GetToolkitDescription and
+// GetFlatTools, and providers with structured tool outputs (such as the MCP server's
+// `outputSchema`) forward it to the model. Use OutputSchemaOf to read it from any Child.
+type TypedChild interface {
+	Child
+
+	// GetOutputSchema returns the JSON schema definition of the results this tool
+	// returns, or nil if the result shape is unknown.
+	GetOutputSchema() interface{}
+}
+
+// MutableParent is implemented by parents whose children can change while requests are
+// served, such as the parents created with NewParent. Toolkit.AddChild and
+// Toolkit.RemoveChild use it, so prefer them: they also notify the toolkit's change
+// listeners (see Toolkit.OnChange).
+type MutableParent interface {
+	Parent
+
+	// AddChild registers child, replacing any child with the same name.
+	AddChild(child Child)
+
+	// RemoveChild unregisters the named child and reports whether it was registered.
+	RemoveChild(name string) bool
+}
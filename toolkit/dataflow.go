@@ -0,0 +1,457 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file implements inter-child data flow: child arguments may reference the results of
+// other children of the same request, and the request is executed as a dependency DAG.
+package toolkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+)
+
+// refKey is the property name of a reference object inside child arguments.
+const refKey = "$ref"
+
+// flowNode is a child request taking part in a data flow execution.
+type flowNode struct {
+	parentIdx  int                // Position of the parent in the request
+	childIdx   int                // Position of the child within its parent request
+	parent     string             // Parent name
+	child      string             // Child name
+	occurrence int                // Occurrence of this parent.child pair in the request
+	req        ToolKitChild       // The original child request
+	args       interface{}        // Decoded arguments, when they contain references
+	refs       map[string]flowRef // References of args, by reference string
+	deps       []int              // Nodes whose results are referenced
+	err        error              // Error found while planning (unresolved reference or cycle)
+	resp       ChildResponse      // The child response, once executed
+	done       chan struct{}      // Closed once resp is set
+}
+
+// flowRef is a resolved reference: the referenced node and a JSON pointer into its result.
+type flowRef struct {
+	target  int
+	pointer string
+}
+
+// flowPlan is the dependency graph of a request containing references.
+type flowPlan struct {
+	nodes []*flowNode
+	order []int // Topological order, stable with respect to the request order
+}
+
+// planDataFlow builds the dependency graph of a request. It returns nil when no child
+// argument contains a reference, so plain requests keep the regular execution path.
+//
+// A reference is an object with a single "$ref" string property:
+//
+//	{"$ref": "<parent>.<child>#<occurrence>/<json pointer>"}
+//
+// The occurrence counts the requests of that parent.child pair in the request, starting at 0;
+// it defaults to 0 when "#" is omitted. The optional JSON pointer (RFC 6901) selects a value
+// within the referenced child's result; without it the whole result is used.
+func (t *Toolkit) planDataFlow(request ToolKit) *flowPlan {
+	plan := &flowPlan{}
+	index := map[string]int{}
+	pairs := map[string]bool{}
+	occurrences := map[string]int{}
+	hasRefs := false
+
+	for pi, parentReq := range request.ToolKitParents {
+		if _, ok := t.parent(parentReq.Name); !ok {
+			continue // Unknown parents are reported by handleParent; references to them stay unresolved
+		}
+		for ci, childReq := range parentReq.ToolKitChilds {
+			pair := parentReq.Name + "." + childReq.Name
+			node := &flowNode{
+				parentIdx:  pi,
+				childIdx:   ci,
+				parent:     parentReq.Name,
+				child:      childReq.Name,
+				occurrence: occurrences[pair],
+				req:        childReq,
+				done:       make(chan struct{}),
+			}
+			occurrences[pair]++
+			pairs[pair] = true
+			index[pair+"#"+strconv.Itoa(node.occurrence)] = len(plan.nodes)
+
+			if bytes.Contains(childReq.Args, []byte(refKey)) {
+				dec := json.NewDecoder(bytes.NewReader(childReq.Args))
+				dec.UseNumber()
+				if dec.Decode(&node.args) == nil && containsRef(node.args) {
+					hasRefs = true
+				} else {
+					node.args = nil
+				}
+			}
+			plan.nodes = append(plan.nodes, node)
+		}
+	}
+	if !hasRefs {
+		return nil
+	}
+
+	// Resolve references to nodes
+	for _, node := range plan.nodes {
+		walkRefs(node.args, func(ref string) {
+			if _, seen := node.refs[ref]; seen || node.err != nil {
+				return
+			}
+			resolved, err := parseRef(ref, pairs, index)
+			if err != nil {
+				node.err = err
+				return
+			}
+			if node.refs == nil {
+				node.refs = map[string]flowRef{}
+			}
+			node.refs[ref] = resolved
+			node.deps = append(node.deps, resolved.target)
+		})
+	}
+
+	plan.order = plan.topologicalOrder()
+	return plan
+}
+
+// topologicalOrder orders the nodes so every node follows its dependencies, preferring
+// request order. Nodes that are part of, or depend on, a cycle get a reference_cycle
+// error and are ordered last without dependencies.
+func (p *flowPlan) topologicalOrder() []int {
+	pending := make([]int, len(p.nodes))
+	dependents := make([][]int, len(p.nodes))
+	for i, node := range p.nodes {
+		pending[i] = len(node.deps)
+		for _, dep := range node.deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+
+	var ready, order []int
+	for i := range p.nodes {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		next := ready[0]
+		ready = ready[1:]
+		order = append(order, next)
+		for _, dependent := range dependents[next] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(p.nodes) {
+		var cyclic []string
+		for i, node := range p.nodes {
+			if pending[i] > 0 {
+				cyclic = append(cyclic, node.name())
+			}
+		}
+		for i, node := range p.nodes {
+			if pending[i] > 0 {
+				node.err = NewError(CodeReferenceCycle, fmt.Sprintf(
+					"Child '%s' is part of or depends on a reference cycle between: %s", node.name(), strings.Join(cyclic, ", ")))
+				node.deps = nil
+				order = append(order, i)
+			}
+		}
+	}
+	return order
+}
+
+// name returns the reference name of a node, e.g. "operations.read_file#0".
+func (n *flowNode) name() string {
+	return fmt.Sprintf("%s.%s#%d", n.parent, n.child, n.occurrence)
+}
+
+// processDataFlow executes a request containing references. Children run as soon as the
+// children they reference have finished: in request order in ExecutionSequential mode,
+// concurrently in ExecutionConcurrent mode. Each child is executed through its parent's
+// HandleChildren, so validation, timeouts and error handling are unchanged; the parent
+// timeout applies to each child separately.
+func (t *Toolkit) processDataFlow(ctx context.Context, request ToolKit, plan *flowPlan) ToolKitResponse {
+	resp := ToolKitResponse{Name: t.GetToolkitName(), Responses: make([]ParentResponse, len(request.ToolKitParents))}
+	parentCtxs := make([]context.Context, len(request.ToolKitParents))
+	for pi, parentReq := range request.ToolKitParents {
+		if _, ok := t.parent(parentReq.Name); !ok {
+			resp.Responses[pi] = t.handleParent(ctx, pi, parentReq)
+			continue
+		}
+		// Children of a parent interleave with other parents' children, so the parent
+		// span covers the whole data flow execution
+		parentCtx, span := startParentSpan(withEventParent(ctx, pi), parentReq.Name, len(parentReq.ToolKitChilds))
+		defer func(pi int) { endParentSpan(span, resp.Responses[pi]) }(pi)
+		parentCtxs[pi] = parentCtx
+		EmitEvent(parentCtx, Event{Type: EventParentStarted, Parent: parentReq.Name, ParentIndex: pi})
+		resp.Responses[pi] = ParentResponse{Name: parentReq.Name, ChildsResponses: make([]ChildResponse, len(parentReq.ToolKitChilds))}
+	}
+
+	if !executionFrom(ctx).concurrent() {
+		for _, i := range plan.order {
+			t.runFlowNode(parentCtxs[plan.nodes[i].parentIdx], plan, plan.nodes[i])
+		}
+	} else {
+		var wg sync.WaitGroup
+		for _, node := range plan.nodes {
+			wg.Add(1)
+			go func(node *flowNode) {
+				defer wg.Done()
+				for _, dep := range node.deps {
+					select {
+					case <-plan.nodes[dep].done:
+					case <-ctx.Done():
+					}
+				}
+				t.runFlowNode(parentCtxs[node.parentIdx], plan, node)
+			}(node)
+		}
+		wg.Wait()
+	}
+
+	for _, node := range plan.nodes {
+		resp.Responses[node.parentIdx].ChildsResponses[node.childIdx] = node.resp
+	}
+	return resp
+}
+
+// runFlowNode resolves the references of a node and executes it.
+func (t *Toolkit) runFlowNode(ctx context.Context, plan *flowPlan, node *flowNode) {
+	defer close(node.done)
+	ctx = withEventChild(ctx, node.childIdx)
+
+	exec := executionFrom(ctx)
+	if exec.stopped() {
+		node.resp = exec.skippedResponse(node.child)
+		EmitEvent(ctx, childFinishedEvent(node.parent, node.childIdx, node.resp))
+		return
+	}
+	fail := func(err error) {
+		node.resp = NewChildError(node.child, err)
+		exec.fail(node.parent, node.child)
+		EmitEvent(ctx, childFinishedEvent(node.parent, node.childIdx, node.resp))
+	}
+	if node.err != nil {
+		fail(node.err)
+		return
+	}
+	if err := ctx.Err(); err != nil {
+		fail(contextError(node.child, err))
+		return
+	}
+
+	req := node.req
+	if node.args != nil {
+		resolved, err := resolveRefs(node.args, func(ref string) (interface{}, error) {
+			resolved := node.refs[ref]
+			dep := plan.nodes[resolved.target]
+			if dep.resp.Failed() {
+				return nil, fmt.Errorf("referenced child '%s' failed: %v", dep.name(), dep.resp.Err())
+			}
+			value, err := toJSONValue(dep.resp.Result)
+			if err != nil {
+				return nil, fmt.Errorf("result of '%s' is not JSON: %v", dep.name(), err)
+			}
+			return resolvePointer(value, resolved.pointer)
+		})
+		if err != nil {
+			fail(NewError(CodeUnresolvedReference, fmt.Sprintf("Arguments of '%s' could not be resolved: %v", node.name(), err)))
+			return
+		}
+		raw, err := json.Marshal(resolved)
+		if err != nil {
+			fail(NewError(CodeUnresolvedReference, fmt.Sprintf("Arguments of '%s' could not be encoded: %v", node.name(), err)))
+			return
+		}
+		req.Args = raw
+	}
+
+	// The parent may have been removed since the request was planned
+	parent, ok := t.parent(node.parent)
+	if !ok {
+		fail(t.parentNotFound(node.parent))
+		return
+	}
+	parentResp := parent.HandleChildren(ctx, []ToolKitChild{req})
+	if len(parentResp.ChildsResponses) == 0 {
+		fail(NewError(CodeHandlerExecutionError, fmt.Sprintf("Parent '%s' returned no response for '%s'", node.parent, node.child)))
+		return
+	}
+	node.resp = parentResp.ChildsResponses[0]
+	if node.resp.Failed() {
+		exec.fail(node.parent, node.child)
+	}
+}
+
+// parseRef splits a reference into its target node and JSON pointer. pairs holds the
+// "parent.child" pairs of the request and index maps "parent.child#n" to node indices.
+func parseRef(ref string, pairs map[string]bool, index map[string]int) (flowRef, error) {
+	target, fragment, hasFragment := strings.Cut(ref, "#")
+	occurrence, pointer := "0", ""
+	if hasFragment {
+		occurrence = fragment
+		if slash := strings.Index(fragment, "/"); slash >= 0 {
+			occurrence, pointer = fragment[:slash], fragment[slash:]
+		}
+	}
+	if _, err := strconv.Atoi(occurrence); err != nil {
+		return flowRef{}, NewError(CodeUnresolvedReference, fmt.Sprintf("Reference '%s' has an invalid occurrence '%s'; expected <parent>.<child>#<n>/<pointer>", ref, occurrence))
+	}
+	if !pairs[target] {
+		return flowRef{}, NewError(CodeUnresolvedReference, fmt.Sprintf("Reference '%s' does not match any child of a registered parent in this request", ref))
+	}
+	i, ok := index[target+"#"+occurrence]
+	if !ok {
+		return flowRef{}, NewError(CodeUnresolvedReference, fmt.Sprintf("Reference '%s' points to occurrence %s of '%s', which is not in this request", ref, occurrence, target))
+	}
+	return flowRef{target: i, pointer: pointer}, nil
+}
+
+// --- Reference Helpers ---
+
+// refArgsSchema returns a copy of a child args schema in which every property also accepts
+// a reference object, so clients that enforce the schema let models send references.
+// The properties of args are shared with the child's schema and are not modified.
+func refArgsSchema(args *jsonschema.Schema) *jsonschema.Schema {
+	if args.Properties == nil || args.Properties.Len() == 0 {
+		return args
+	}
+	withRefs := *args
+	withRefs.Properties = jsonschema.NewProperties()
+	for pair := args.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		withRefs.Properties.Set(pair.Key, &jsonschema.Schema{AnyOf: []*jsonschema.Schema{pair.Value, refObjectSchema()}})
+	}
+	return &withRefs
+}
+
+// refObjectSchema returns the schema of a reference object.
+func refObjectSchema() *jsonschema.Schema {
+	s := objectSchema(prop(refKey, &jsonschema.Schema{
+		Type:        "string",
+		Description: "The result of another child of this request: <parent>.<child>#<n>/<json pointer>.",
+	}))
+	s.AdditionalProperties = jsonschema.FalseSchema
+	return s
+}
+
+// refString returns the reference of a value if it is a reference object.
+func refString(value interface{}) (string, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return "", false
+	}
+	ref, ok := obj[refKey].(string)
+	return ref, ok
+}
+
+// containsRef reports whether a decoded JSON value contains a reference object.
+func containsRef(value interface{}) bool {
+	found := false
+	walkRefs(value, func(string) { found = true })
+	return found
+}
+
+// walkRefs calls fn for every reference object in a decoded JSON value.
+func walkRefs(value interface{}, fn func(ref string)) {
+	if ref, ok := refString(value); ok {
+		fn(ref)
+		return
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			walkRefs(v[key], fn)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walkRefs(item, fn)
+		}
+	}
+}
+
+// resolveRefs returns a copy of a decoded JSON value with every reference object
+// replaced by the value returned by resolve.
+func resolveRefs(value interface{}, resolve func(ref string) (interface{}, error)) (interface{}, error) {
+	if ref, ok := refString(value); ok {
+		return resolve(ref)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := resolveRefs(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := resolveRefs(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// toJSONValue converts a child result into its decoded JSON representation.
+func toJSONValue(result interface{}) (interface{}, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// resolvePointer evaluates a JSON pointer (RFC 6901) against a decoded JSON value.
+func resolvePointer(value interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	current := value
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("pointer '%s': property '%s' not found", pointer, token)
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("pointer '%s': index '%s' out of range", pointer, token)
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("pointer '%s': cannot select '%s' in a %s", pointer, token, jsonTypeOf(current))
+		}
+	}
+	return current, nil
+}
@@ -0,0 +1,94 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the size budget of GetToolkitDescription and the schema shortening applied
+// when a description exceeds it, so large toolkits still fit into the model's context.
+package toolkit
+
+import "encoding/json"
+
+// bytesPerToken is the estimate used to convert DescriptionBudget.MaxTokens into bytes.
+// JSON schemas tokenize at roughly four bytes per token for current models.
+const bytesPerToken = 4
+
+// DescriptionBudget limits the size of GetToolkitDescription (see WithDescriptionBudget).
+// When the full description exceeds the budget, the child schemas are shortened step by step:
+//  1. Descriptions and `$schema`/`$id` headers are dropped from the schemas
+//  2. Nested objects are collapsed to {"type":"object"}, keeping the top-level properties
+//  3. Output schemas are omitted
+//
+// The first step that fits the budget is used. If none does, the shortest description is
+// returned and a warning is logged.
+type DescriptionBudget struct {
+	MaxBytes  int // Maximum size in bytes; zero means no limit
+	MaxTokens int // Maximum size in tokens, estimated at four bytes per token; zero means no limit
+}
+
+// limit returns the budget in bytes, or zero if there is none.
+func (b DescriptionBudget) limit() int {
+	limit := b.MaxBytes
+	if tokens := b.MaxTokens * bytesPerToken; tokens > 0 && (limit <= 0 || tokens < limit) {
+		limit = tokens
+	}
+	return max(limit, 0)
+}
+
+// descriptionDetail is a shortening step of the toolkit description.
+type descriptionDetail int
+
+const (
+	detailFull            descriptionDetail = iota // Schemas as provided by the children
+	detailNoDescriptions                           // Without schema descriptions and headers
+	detailCollapsed                                // Additionally with nested objects collapsed
+	detailNoOutputSchemas                          // Additionally without output schemas
+)
+
+// describeSchema encodes a child schema for the toolkit description at the given detail.
+func describeSchema(schema interface{}, detail descriptionDetail) ([]byte, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil || detail == detailFull {
+		return raw, err
+	}
+	var node interface{}
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return raw, nil
+	}
+	return json.Marshal(shortenSchema(node, detail, 0))
+}
+
+// shortenSchema drops the descriptions of a decoded schema and, from detailCollapsed on,
+// replaces object schemas below the top level with {"type":"object"}.
+func shortenSchema(node interface{}, detail descriptionDetail, depth int) interface{} {
+	s, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+	if detail >= detailCollapsed && depth > 0 {
+		if _, nested := s["properties"]; nested || s["type"] == "object" {
+			return map[string]interface{}{"type": "object"}
+		}
+	}
+
+	delete(s, "description")
+	delete(s, "$schema")
+	delete(s, "$id")
+	for _, key := range []string{"properties", "patternProperties", "$defs", "definitions"} {
+		if props, ok := s[key].(map[string]interface{}); ok {
+			for name, sub := range props {
+				props[name] = shortenSchema(sub, detail, depth+1)
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := s[key]; ok {
+			s[key] = shortenSchema(sub, detail, depth+1)
+		}
+	}
+	// Alternatives describe the same value, so they stay at the same depth
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if subs, ok := s[key].([]interface{}); ok {
+			for i, sub := range subs {
+				subs[i] = shortenSchema(sub, detail, depth)
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file defines the progress events emitted while a request executes, which let UIs show
+// progress and forward partial results before the slowest child has finished.
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	// EventParentStarted is emitted when a parent of the request starts executing.
+	EventParentStarted EventType = "parent_started"
+	// EventChildStarted is emitted when a child of the request starts executing.
+	EventChildStarted EventType = "child_started"
+	// EventChildFinished is emitted with the response of every child of the request,
+	// including error responses for unknown parents and children.
+	EventChildFinished EventType = "child_finished"
+	// EventToolkitDone is emitted once with the complete response of HandleToolKit or
+	// HandleFlatTool, including requests that failed before any child started.
+	EventToolkitDone EventType = "toolkit_done"
+)
+
+// Event reports the progress of a toolkit request.
+type Event struct {
+	Type        EventType        // The kind of event
+	Parent      string           // Parent name (all events but EventToolkitDone)
+	ParentIndex int              // Position of the parent in the request
+	Child       string           // Child name (child events)
+	ChildIndex  int              // Position of the child within its parent request (child events)
+	Response    *ChildResponse   // The child response (EventChildFinished)
+	Err         error            // The child's error (EventChildFinished) or the request error (EventToolkitDone)
+	Result      *ToolKitResponse // The complete response (EventToolkitDone)
+	Time        time.Time        // When the event occurred
+}
+
+// eventHandlerKey is the context key under which the event emitter is stored.
+type eventHandlerKey struct{}
+
+// eventParentKey is the context key under which the request index of a parent is stored.
+type eventParentKey struct{}
+
+// eventChildKey is the context key under which the request index of a child executed on
+// its own is stored, so data flow execution reports the child's position in the request.
+type eventChildKey struct{}
+
+// eventEmitter serializes calls to an event handler.
+type eventEmitter struct {
+	mu sync.Mutex
+	fn func(Event)
+}
+
+// ContextWithEventHandler returns a copy of ctx that reports the progress of HandleToolKit
+// and HandleFlatTool to fn. The built-in parents and children emit all events; custom
+// Parent implementations only get parent events unless they call EmitEvent themselves.
+//
+// fn is never called concurrently, even in ExecutionConcurrent mode, but it runs on the
+// execution path: keep it fast, or forward the events to a buffered channel.
+//
+// Example:
+//
+//	ctx := toolkit.ContextWithEventHandler(ctx, func(ev toolkit.Event) {
+//	    if ev.Type == toolkit.EventChildFinished {
+//	        fmt.Printf("%s.%s finished\n", ev.Parent, ev.Child)
+//	    }
+//	})
+//	resp, err := myToolkit.HandleToolKit(ctx, input)
+func ContextWithEventHandler(ctx context.Context, fn func(Event)) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, &eventEmitter{fn: fn})
+}
+
+// EmitEvent reports an event to the handler attached with ContextWithEventHandler, if any.
+// The event time and parent index are filled in when missing. Custom Parent
+// implementations use it to report their children's progress.
+func EmitEvent(ctx context.Context, ev Event) {
+	e, ok := ctx.Value(eventHandlerKey{}).(*eventEmitter)
+	if !ok || e.fn == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Type != EventToolkitDone && ev.ParentIndex == 0 {
+		ev.ParentIndex, _ = ctx.Value(eventParentKey{}).(int)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fn(ev)
+}
+
+// withEventParent records the request index of the parent executing under ctx.
+// The index is also used to locate the responses of children that are rolled back.
+func withEventParent(ctx context.Context, index int) context.Context {
+	return context.WithValue(ctx, eventParentKey{}, index)
+}
+
+// withEventChild records the request index of the single child executing under ctx.
+func withEventChild(ctx context.Context, index int) context.Context {
+	return context.WithValue(ctx, eventChildKey{}, index)
+}
+
+// eventChildIndex returns the child index recorded by withEventChild, or index if none is.
+func eventChildIndex(ctx context.Context, index int) int {
+	if override, ok := ctx.Value(eventChildKey{}).(int); ok && override >= 0 {
+		return override
+	}
+	return index
+}
+
+// childFinishedEvent builds the EventChildFinished event of a child response.
+func childFinishedEvent(parent string, index int, resp ChildResponse) Event {
+	return Event{Type: EventChildFinished, Parent: parent, Child: resp.Name, ChildIndex: index, Response: &resp, Err: resp.Err()}
+}
+
+// HandleToolKitStream executes a request like HandleToolKit and reports its progress to fn
+// as it happens (see ContextWithEventHandler). The final EventToolkitDone event carries the
+// same response and error that are returned.
+//
+// Example:
+//
+//	resp, err := myToolkit.HandleToolKitStream(ctx, input, func(ev toolkit.Event) {
+//	    if ev.Type == toolkit.EventChildFinished {
+//	        forwardPartialResult(ev.Parent, ev.Child, ev.Response)
+//	    }
+//	})
+func (t *Toolkit) HandleToolKitStream(ctx context.Context, input json.RawMessage, fn func(Event)) (ToolKitResponse, error) {
+	return t.HandleToolKit(ContextWithEventHandler(ctx, fn), input)
+}
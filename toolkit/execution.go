@@ -0,0 +1,281 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the execution settings that a Toolkit passes down to its parents
+// through the request context, such as the execution mode, the concurrency limit and
+// per-request timeout overrides, and the isolation of every child invocation from
+// deadlines and panics.
+package toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExecutionMode controls how the parents and children of a single request are executed.
+type ExecutionMode int
+
+const (
+	// ExecutionSequential runs every parent, and every child within a parent,
+	// one after another in request order. This is the default mode.
+	ExecutionSequential ExecutionMode = iota
+
+	// ExecutionConcurrent runs all requested parents, and the children within each
+	// parent, concurrently. Responses are still reported in request order.
+	ExecutionConcurrent
+)
+
+// execution holds the settings shared by every parent and child of one HandleToolKit call.
+// It travels through the context so that the Parent interface does not need to change.
+type execution struct {
+	mode   ExecutionMode
+	slots  chan struct{}   // Bounded semaphore for child executions; nil means unbounded
+	policy ExecutionPolicy // What happens after a child fails (see ExecutionPolicy)
+	mw     []Middleware    // Toolkit middleware run around every child (see WithMiddleware)
+	tracer Tracer          // Tracer of the toolkit (see WithTracer); nil means none
+
+	toolkit string  // Toolkit name reported in metrics observations
+	metrics Metrics // Metrics of the toolkit (see WithMetrics); nil means none
+
+	mu        sync.Mutex
+	failure   string           // "parent.child" of the first failed child; empty while none failed
+	completed []completedChild // Successful children to roll back under PolicyAllOrNothing
+}
+
+// executionKey is the context key under which the current execution is stored.
+type executionKey struct{}
+
+// newExecution creates the execution settings for one request.
+// A maxConcurrency of zero or less leaves the number of concurrent children unbounded.
+func newExecution(mode ExecutionMode, maxConcurrency int, policy ExecutionPolicy) *execution {
+	e := &execution{mode: mode, policy: policy}
+	if mode == ExecutionConcurrent && maxConcurrency > 0 {
+		e.slots = make(chan struct{}, maxConcurrency)
+	}
+	return e
+}
+
+// withExecution returns a copy of ctx carrying the given execution settings.
+func withExecution(ctx context.Context, e *execution) context.Context {
+	return context.WithValue(ctx, executionKey{}, e)
+}
+
+// executionFrom returns the execution settings stored in ctx.
+// When a Parent is used outside of a Toolkit, sequential settings are returned.
+func executionFrom(ctx context.Context) *execution {
+	if e, ok := ctx.Value(executionKey{}).(*execution); ok && e != nil {
+		return e
+	}
+	return &execution{mode: ExecutionSequential}
+}
+
+// concurrent reports whether parents and children should be run concurrently.
+func (e *execution) concurrent() bool {
+	return e.mode == ExecutionConcurrent
+}
+
+// acquire blocks until a child execution slot is available or ctx is done.
+// Only child executions take slots, so nested parent goroutines can never deadlock.
+func (e *execution) acquire(ctx context.Context) error {
+	if e.slots == nil {
+		return nil
+	}
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot previously taken with acquire.
+func (e *execution) release() {
+	if e.slots != nil {
+		<-e.slots
+	}
+}
+
+// slotHold keeps a child execution slot taken until all of its holders are done: the child
+// execution that acquired it and every handler goroutine that callWithTimeout abandoned
+// after a deadline, since those keep running until their handler returns.
+type slotHold struct {
+	holders atomic.Int32
+	release func()
+}
+
+// slotHoldKey is the context key under which the slot of the current child is stored.
+type slotHoldKey struct{}
+
+// withSlotHold returns a copy of ctx carrying a slot held once, by the caller, which lets
+// go of it with done. release is called once every holder is done.
+func withSlotHold(ctx context.Context, release func()) (context.Context, *slotHold) {
+	h := &slotHold{release: release}
+	h.holders.Store(1)
+	return context.WithValue(ctx, slotHoldKey{}, h), h
+}
+
+// slotHoldFrom returns the slot of the child executing under ctx, or nil.
+func slotHoldFrom(ctx context.Context) *slotHold {
+	h, _ := ctx.Value(slotHoldKey{}).(*slotHold)
+	return h
+}
+
+// hold adds a holder to the slot. It is a no-op on a nil slot.
+func (h *slotHold) hold() {
+	if h != nil {
+		h.holders.Add(1)
+	}
+}
+
+// done removes a holder from the slot and releases it when it was the last one.
+// It is a no-op on a nil slot.
+func (h *slotHold) done() {
+	if h != nil && h.holders.Add(-1) == 0 {
+		h.release()
+	}
+}
+
+// --- Per-Request Overrides ---
+
+// RequestOptions overrides the timeouts and the execution policy configured on the Toolkit,
+// its Parents and its Children for a single request. Zero values keep the configured settings.
+// Attach them to the request context with ContextWithRequestOptions.
+type RequestOptions struct {
+	Timeout       time.Duration   // Replaces the toolkit timeout (see WithTimeout)
+	ParentTimeout time.Duration   // Replaces every parent timeout (see WithParentTimeout)
+	ChildTimeout  time.Duration   // Replaces every child timeout (see WithChildTimeout)
+	Policy        ExecutionPolicy // Replaces the toolkit execution policy (see WithExecutionPolicy)
+}
+
+// requestOptionsKey is the context key under which RequestOptions are stored.
+type requestOptionsKey struct{}
+
+// ContextWithRequestOptions returns a copy of ctx carrying per-request overrides.
+//
+// Example:
+//
+//	ctx := toolkit.ContextWithRequestOptions(ctx, toolkit.RequestOptions{ChildTimeout: 2 * time.Second})
+//	resp, err := myToolkit.HandleToolKit(ctx, input)
+func ContextWithRequestOptions(ctx context.Context, opts RequestOptions) context.Context {
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// requestOptionsFrom returns the RequestOptions stored in ctx, or the zero value.
+func requestOptionsFrom(ctx context.Context) RequestOptions {
+	opts, _ := ctx.Value(requestOptionsKey{}).(RequestOptions)
+	return opts
+}
+
+// overrideTimeout returns override when it is set, and configured otherwise.
+func overrideTimeout(configured, override time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	return configured
+}
+
+// --- Deadline Enforcement ---
+
+// callWithTimeout runs fn with a context bounded by timeout (if > 0) and by ctx itself.
+// If the context ends before fn returns, a "timeout" or "canceled" ToolKitError is
+// returned right away so a handler that ignores its context cannot block the batch.
+// The late result of such a handler is discarded once it eventually returns; until
+// then it keeps the concurrency slot of its child (see WithMaxConcurrency).
+// A panic in fn is recovered into a "handler_panic" ToolKitError (see recoverPanic).
+func callWithTimeout(ctx context.Context, timeout time.Duration, name string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Without a deadline or cancellation there is nothing to enforce
+	if ctx.Done() == nil {
+		return recoverPanic(ctx, name, fn)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(name, err)
+	}
+
+	type outcome struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan outcome, 1) // Buffered so an abandoned handler never blocks
+	slot := slotHoldFrom(ctx)
+	slot.hold()
+	go func() {
+		defer slot.done()
+		result, err := recoverPanic(ctx, name, fn)
+		done <- outcome{result: result, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil && ctx.Err() != nil && !errors.Is(o.err, ToolKitError{Code: CodeHandlerPanic}) {
+			// The handler gave up because of the context; report it as such
+			return nil, contextError(name, ctx.Err())
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, contextError(name, ctx.Err())
+	}
+}
+
+// enforcesDeadline reports whether a child applies the child timeout, including
+// RequestOptions.ChildTimeout, to each of its attempts in its own Handle method.
+func enforcesDeadline(child Child) bool {
+	c, ok := child.(interface{ enforcesDeadline() bool })
+	return ok && c.enforcesDeadline()
+}
+
+// contextError converts a context error into a ToolKitError for the named tool.
+// Deadline errors map to the retryable "timeout" code, everything else to "canceled".
+func contextError(name string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewError(CodeTimeout, fmt.Sprintf("Tool '%s' did not finish before its deadline: %v", name, err), WithCause(err), WithRetryable(true))
+	}
+	return NewError(CodeCanceled, fmt.Sprintf("Tool '%s' was canceled: %v", name, err), WithCause(err))
+}
+
+// --- Panic Recovery ---
+
+// maxStackFrames bounds the number of stack frames kept in the details of a handler_panic error.
+const maxStackFrames = 16
+
+// recoverPanic calls fn and converts a panic into a "handler_panic" ToolKitError, so a
+// panicking handler fails its own child instead of crashing the process. The panic value and
+// the stack from the panicking function on are kept in the error details and logged.
+func recoverPanic(ctx context.Context, name string, fn func(ctx context.Context) (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			stack := trimStack(debug.Stack())
+			LoggerFromContext(ctx).Error("Child panicked", LogKeyPanic, fmt.Sprint(v), LogKeyStack, stack)
+			result, err = nil, NewError(CodeHandlerPanic, fmt.Sprintf("Tool '%s' panicked: %v", name, v),
+				WithDetails(map[string]any{"panic": fmt.Sprint(v), "stack": stack}))
+		}
+	}()
+	return fn(ctx)
+}
+
+// trimStack drops the frames of the recovery itself from a debug.Stack trace and keeps at most
+// maxStackFrames frames of the panicking code.
+func trimStack(stack []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	// Frames are pairs of a function line and a tab-indented file line. The frame of the
+	// runtime panic function is followed by the frame that panicked.
+	for i := 1; i+1 < len(lines); i += 2 {
+		if strings.HasPrefix(lines[i], "panic(") {
+			lines = lines[i+2:]
+			break
+		}
+	}
+	if len(lines) > 2*maxStackFrames {
+		lines = append(lines[:2*maxStackFrames], "...")
+	}
+	return strings.Join(lines, "\n")
+}
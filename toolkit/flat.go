@@ -0,0 +1,129 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file implements flat mode, which exposes every child as its own top-level tool for models
+// that handle a single hierarchical meta-tool poorly, while reusing the hierarchical execution path.
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// FlatToolSeparator joins the parent and child names of a flat tool (e.g., "file_ops__read_file").
+const FlatToolSeparator = "__"
+
+// FlatTool describes a single child exported as a top-level tool.
+type FlatTool struct {
+	Name        string      `json:"name"`         // Flat tool name: parent name + FlatToolSeparator + child name
+	Description string      `json:"description"`  // The child's description
+	InputSchema interface{} `json:"input_schema"` // The child's input schema, without `$schema`/`$id` headers
+	// The child's output schema (see TypedChild), without `$schema`/`$id` headers; nil if unknown
+	OutputSchema *jsonschema.Schema `json:"output_schema,omitempty"`
+	Parent       string             `json:"-"` // Name of the parent owning the child
+	Child        string             `json:"-"` // Name of the child
+}
+
+// FlatToolName returns the flat tool name of a child.
+func FlatToolName(parentName, childName string) string {
+	return parentName + FlatToolSeparator + childName
+}
+
+// GetFlatTools exports every child of the toolkit as an individual tool, ordered by
+// parent and child name. Each tool carries the child's real input schema, so it can be
+// registered directly with providers that expect one tool per function.
+//
+// Calls to these tools are executed with HandleFlatTool.
+//
+// Example:
+//
+//	for _, tool := range myToolkit.GetFlatTools() {
+//	    tools = append(tools, anthropic.ToolParam{
+//	        Name:        anthropic.F(tool.Name),
+//	        Description: anthropic.F(tool.Description),
+//	        InputSchema: anthropic.F(tool.InputSchema),
+//	    })
+//	}
+func (t *Toolkit) GetFlatTools() []FlatTool {
+	var tools []FlatTool
+	for _, parent := range t.sortedParents() {
+		for _, child := range sortedChildren(parent) {
+			tools = append(tools, FlatTool{
+				Name:         FlatToolName(parent.GetName(), child.GetName()),
+				Description:  child.GetDescription(),
+				InputSchema:  t.childArgsSchema(parent.GetName(), child),
+				OutputSchema: t.childOutputSchema(parent.GetName(), child),
+				Parent:       parent.GetName(),
+				Child:        child.GetName(),
+			})
+		}
+	}
+	return tools
+}
+
+// HandleFlatTool executes a call to a tool exported by GetFlatTools. The call is routed
+// through the same path as HandleToolKit (toolkit timeout, Parent.HandleChildren, argument
+// validation and error handling), so flat and hierarchical mode behave identically.
+//
+// Parameters:
+//   - ctx: Context for cancellation, deadlines and request options
+//   - name: The flat tool name, as returned in FlatTool.Name
+//   - args: The tool call arguments, as a JSON object
+//
+// Returns:
+//   - ChildResponse: The child's result, or a ToolKitError in Error if the call failed
+//   - error: A "tool_not_found" ToolKitError if no child is exported under that name, or nil
+func (t *Toolkit) HandleFlatTool(ctx context.Context, name string, args json.RawMessage) (ChildResponse, error) {
+	ctx, _ = t.requestContext(ctx)
+	ctx, span := t.startToolkitSpan(ctx)
+	resp, err := t.processFlatTool(ctx, name, args)
+	endToolkitSpan(span, resp, err)
+	EmitEvent(ctx, Event{Type: EventToolkitDone, Result: &resp, Err: err})
+	if err != nil {
+		return NewChildError(name, err), err
+	}
+	if len(resp.Responses) == 0 || len(resp.Responses[0].ChildsResponses) == 0 {
+		err := NewError(CodeHandlerExecutionError, fmt.Sprintf("Tool '%s' returned no response", name))
+		return NewChildError(name, err), err
+	}
+	return resp.Responses[0].ChildsResponses[0], nil
+}
+
+// processFlatTool routes a flat tool call to its parent and child through processToolKit.
+// Unknown tool names produce a "tool_not_found" error and an empty response.
+func (t *Toolkit) processFlatTool(ctx context.Context, name string, args json.RawMessage) (ToolKitResponse, error) {
+	parentName, childName, ok := t.resolveFlatTool(name)
+	if !ok {
+		err := NewError(CodeToolNotFound, fmt.Sprintf("Tool '%s' not registered", name))
+		LoggerFromContext(ctx).Warn("Requested flat tool not found", append([]interface{}{"tool", name}, errorAttrs(err)...)...)
+		// The request has no execution yet, so the toolkit metrics are called directly
+		if t.metrics != nil {
+			t.metrics.ObserveChild(ChildObservation{Toolkit: t.name, Child: name, ErrorCode: CodeToolNotFound})
+		}
+		return ToolKitResponse{Name: t.GetToolkitName()}, err
+	}
+	return t.processToolKit(ctx, ToolKit{
+		Name: t.name,
+		ToolKitParents: []ToolKitParent{
+			{Name: parentName, ToolKitChilds: []ToolKitChild{{Name: childName, Args: args}}},
+		},
+	})
+}
+
+// resolveFlatTool maps a flat tool name to its parent and child names. Names are
+// matched against the registered children, so parent or child names that contain
+// the separator themselves are still resolved correctly.
+func (t *Toolkit) resolveFlatTool(name string) (parentName, childName string, ok bool) {
+	for _, parent := range t.sortedParents() {
+		prefix := parent.GetName() + FlatToolSeparator
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if _, exists := parent.GetChildren()[strings.TrimPrefix(name, prefix)]; exists {
+			return parent.GetName(), strings.TrimPrefix(name, prefix), true
+		}
+	}
+	return "", "", false
+}
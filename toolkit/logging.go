@@ -0,0 +1,144 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the structured logging integration: the injectable *slog.Logger, the
+// attribute keys shared by every log record, call IDs and helpers to redact sensitive values.
+package toolkit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+)
+
+// Attribute keys used by every log record of the toolkit.
+const (
+	LogKeyToolkit   = "toolkit"    // Toolkit name
+	LogKeyParent    = "parent"     // Parent name
+	LogKeyChild     = "child"      // Child name
+	LogKeyCallID    = "call_id"    // ID of the HandleToolKit call (see ContextWithCallID)
+	LogKeyDuration  = "duration"   // Duration of a child or request execution
+	LogKeyErrorCode = "error_code" // ToolKitError code of a failure
+	LogKeyError     = "error"      // Error message of a failure, which may quote arguments
+	LogKeyArgsBytes = "args_bytes" // Size of the child arguments; the arguments themselves are never logged
+	LogKeyAttempt   = "attempt"    // Number of a failed attempt that is retried (see RetryPolicy)
+	LogKeyBackoff   = "backoff"    // Delay before the next attempt
+	LogKeyPanic     = "panic"      // Value of a recovered handler panic
+	LogKeyStack     = "stack"      // Trimmed stack of a recovered handler panic
+)
+
+// loggerKey is the context key under which the logger of the current execution is stored.
+type loggerKey struct{}
+
+// callIDKey is the context key under which the call ID of a request is stored.
+type callIDKey struct{}
+
+// discardHandler is a slog.Handler that drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// discardLogger returns a logger that drops every record.
+func discardLogger() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+// Logger returns the logger of the toolkit (see WithLogger), with the toolkit name attached.
+// Packages built on the toolkit, such as the MCP server, log through it.
+func (t *Toolkit) Logger() *slog.Logger {
+	logger := t.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return logger.With(LogKeyToolkit, t.name)
+}
+
+// ContextWithCallID returns a copy of ctx that makes HandleToolKit log under the given call
+// ID, such as the tool_use ID chosen by the model. Without it a random ID is generated.
+func ContextWithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+// CallIDFromContext returns the call ID of the request executing under ctx, or "" outside of a request.
+func CallIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(callIDKey{}).(string)
+	return id
+}
+
+// LoggerFromContext returns the logger of the execution running under ctx. Within a child
+// handler it carries the toolkit, parent, child and call ID attributes, so handlers log with
+// the same attributes as the toolkit. Outside of a toolkit, slog.Default() is returned.
+//
+// Example:
+//
+//	func readFile(ctx context.Context, args ReadFileArgs) (interface{}, error) {
+//	    toolkit.LoggerFromContext(ctx).Debug("Reading file", "path", args.Path)
+//	    ...
+//	}
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// RedactLogAttrs returns a slog.HandlerOptions.ReplaceAttr function that replaces the values
+// of the given attribute keys with "[REDACTED]". Use it to keep error messages, which may quote
+// argument values or handler errors, out of the logs.
+//
+// Example:
+//
+//	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+//	    ReplaceAttr: toolkit.RedactLogAttrs(toolkit.LogKeyError),
+//	})
+//	tk := toolkit.NewWithOptions("my_toolkit", []toolkit.Option{toolkit.WithLogger(slog.New(handler))}, parents...)
+func RedactLogAttrs(keys ...string) func(groups []string, a slog.Attr) slog.Attr {
+	redacted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		redacted[key] = true
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if redacted[a.Key] {
+			return slog.String(a.Key, "[REDACTED]")
+		}
+		return a
+	}
+}
+
+// requestContext prepares ctx for a request: it assigns a call ID if ctx has none yet and
+// attaches the toolkit logger with that call ID.
+func (t *Toolkit) requestContext(ctx context.Context) (context.Context, *slog.Logger) {
+	id := CallIDFromContext(ctx)
+	if id == "" {
+		id = newCallID()
+		ctx = ContextWithCallID(ctx, id)
+	}
+	logger := t.Logger().With(LogKeyCallID, id)
+	return withLogger(ctx, logger), logger
+}
+
+// withLogger returns a copy of ctx carrying the logger of the current execution.
+func withLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// newCallID returns a random call ID.
+func newCallID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// errorAttrs returns the log attributes describing err.
+func errorAttrs(err error) []interface{} {
+	var tkErr ToolKitError
+	if errors.As(err, &tkErr) {
+		return []interface{}{LogKeyErrorCode, tkErr.Code, LogKeyError, tkErr.Message}
+	}
+	return []interface{}{LogKeyError, err.Error()}
+}
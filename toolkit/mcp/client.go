@@ -0,0 +1,485 @@
+// Package mcp connects toolkits to the Model Context Protocol (MCP).
+// This file implements the client side: a Client speaking MCP over stdio (a subprocess
+// or any pipe pair) or streamable HTTP, used to import remote tools as toolkit children.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+)
+
+// clientInfo identifies this package to MCP servers.
+var clientInfo = Implementation{Name: "ai-toolkit", Version: "0.1.0"}
+
+// Client is a connection to an MCP server. It is created with DialCommand, DialHTTP
+// or NewStdioClient, which perform the MCP initialization handshake, and must be
+// closed with Close. A Client is safe for concurrent use.
+type Client struct {
+	transport       transport
+	nextID          atomic.Int64
+	serverInfo      Implementation
+	protocolVersion string
+}
+
+// transport sends JSON-RPC messages to a server.
+type transport interface {
+	// call sends a request and waits for its response.
+	call(ctx context.Context, req message) (message, error)
+	// notify sends a notification.
+	notify(ctx context.Context, msg message) error
+	// setProtocolVersion records the negotiated protocol version.
+	setProtocolVersion(version string)
+	// close releases the connection.
+	close() error
+}
+
+// DialCommand starts an MCP server as a subprocess and connects to it over its
+// standard input and output. The server's standard error is forwarded to os.Stderr.
+// Closing the client stops the subprocess.
+//
+// Example:
+//
+//	client, err := mcp.DialCommand(ctx, "npx", "-y", "@modelcontextprotocol/server-filesystem", "/tmp")
+//	if err != nil { ... }
+//	defer client.Close()
+func DialCommand(ctx context.Context, command string, args ...string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating stdin pipe for MCP server '%s': %w", command, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating stdout pipe for MCP server '%s': %w", command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting MCP server '%s': %w", command, err)
+	}
+
+	t := newStdioTransport(stdout, stdin)
+	t.onClose = func() error {
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			slog.Default().Warn("MCP client: server did not exit after stdin was closed, killing it", "command", command)
+			_ = cmd.Process.Kill()
+			<-done
+		}
+		return nil
+	}
+	return connect(ctx, t)
+}
+
+// NewStdioClient connects to an MCP server over an existing pipe pair: responses are
+// read from r and requests written to w. Closing the client closes w if it is an io.Closer.
+func NewStdioClient(ctx context.Context, r io.Reader, w io.Writer) (*Client, error) {
+	return connect(ctx, newStdioTransport(r, w))
+}
+
+// DialHTTP connects to an MCP server over the streamable HTTP transport at url.
+// A nil httpClient uses http.DefaultClient.
+func DialHTTP(ctx context.Context, url string, httpClient *http.Client) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return connect(ctx, &httpTransport{url: url, client: httpClient})
+}
+
+// connect performs the MCP initialization handshake over a transport.
+func connect(ctx context.Context, t transport) (*Client, error) {
+	c := &Client{transport: t}
+
+	var result initializeResult
+	err := c.request(ctx, "initialize", initializeParams{
+		ProtocolVersion: LatestProtocolVersion,
+		Capabilities:    json.RawMessage(`{}`),
+		ClientInfo:      clientInfo,
+	}, &result)
+	if err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("error initializing MCP session: %w", err)
+	}
+	supported := false
+	for _, version := range supportedProtocolVersions {
+		supported = supported || result.ProtocolVersion == version
+	}
+	if !supported {
+		_ = t.close()
+		return nil, fmt.Errorf("unsupported MCP protocol version '%s'", result.ProtocolVersion)
+	}
+	c.serverInfo = result.ServerInfo
+	c.protocolVersion = result.ProtocolVersion
+	t.setProtocolVersion(result.ProtocolVersion)
+
+	if err := t.notify(ctx, message{JSONRPC: jsonrpcVersion, Method: "notifications/initialized"}); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("error initializing MCP session: %w", err)
+	}
+	return c, nil
+}
+
+// ServerInfo returns the name and version reported by the server.
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// ListTools returns every tool of the server, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.request(ctx, "tools/list", listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls a tool of the server. Tool failures are returned as a result with
+// IsError set; the error is only non-nil for protocol or transport failures.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.request(ctx, "tools/call", callToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close ends the session and releases the connection.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// request sends a request and decodes its result into v.
+func (c *Client) request(ctx context.Context, method string, params, v interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("error marshaling %s params: %w", method, err)
+	}
+	id := json.RawMessage(fmt.Sprint(c.nextID.Add(1)))
+	resp, err := c.transport.call(ctx, message{JSONRPC: jsonrpcVersion, ID: id, Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, v); err != nil {
+		return fmt.Errorf("error unmarshaling %s result: %w", method, err)
+	}
+	return nil
+}
+
+// --- Stdio Transport ---
+
+// stdioTransport exchanges newline-delimited JSON-RPC messages over a pipe pair.
+// A reader goroutine routes responses to the pending calls by ID.
+type stdioTransport struct {
+	w       io.Writer
+	writeMu sync.Mutex
+	onClose func() error
+
+	mu      sync.Mutex
+	pending map[string]chan message
+	readErr error // set once the reader stopped; fails all later calls
+	closed  chan struct{}
+}
+
+func newStdioTransport(r io.Reader, w io.Writer) *stdioTransport {
+	t := &stdioTransport{w: w, pending: map[string]chan message{}, closed: make(chan struct{})}
+	go t.readLoop(r)
+	return t
+}
+
+// readLoop dispatches incoming messages until r fails or reaches EOF.
+func (t *stdioTransport) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			slog.Default().Warn("MCP client: ignoring malformed message", toolkit.LogKeyError, err.Error())
+			continue
+		}
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			t.answerServerRequest(msg)
+		case msg.Method != "":
+			// Server notifications (logging, list changes, progress) are not used
+		default:
+			t.mu.Lock()
+			ch, ok := t.pending[string(msg.ID)]
+			delete(t.pending, string(msg.ID))
+			t.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	t.mu.Lock()
+	t.readErr = fmt.Errorf("MCP server connection closed: %w", err)
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	close(t.closed)
+}
+
+// answerServerRequest replies to requests sent by the server. Only ping is supported.
+func (t *stdioTransport) answerServerRequest(msg message) {
+	resp := message{JSONRPC: jsonrpcVersion, ID: msg.ID}
+	if msg.Method == "ping" {
+		resp.Result = json.RawMessage(`{}`)
+	} else {
+		resp.Error = newRPCError(codeMethodNotFound, "Method not found: %s", msg.Method)
+	}
+	if err := t.write(resp); err != nil {
+		slog.Default().Warn("MCP client: error answering server request", "method", msg.Method, toolkit.LogKeyError, err.Error())
+	}
+}
+
+func (t *stdioTransport) call(ctx context.Context, req message) (message, error) {
+	ch := make(chan message, 1)
+	key := string(req.ID)
+	t.mu.Lock()
+	if t.readErr != nil {
+		t.mu.Unlock()
+		return message{}, t.readErr
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+		return message{}, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return message{}, t.readErr
+		}
+		return resp, nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+		// Let the server stop working on the abandoned request
+		params, _ := json.Marshal(cancelledParams{RequestID: req.ID, Reason: ctx.Err().Error()})
+		_ = t.notify(context.Background(), message{JSONRPC: jsonrpcVersion, Method: "notifications/cancelled", Params: params})
+		return message{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, msg message) error {
+	return t.write(msg)
+}
+
+func (t *stdioTransport) setProtocolVersion(string) {}
+
+// write sends a single message as one line.
+func (t *stdioTransport) write(msg message) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshaling MCP message: %w", err)
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.w.Write(append(raw, '\n')); err != nil {
+		return fmt.Errorf("error writing MCP message: %w", err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) close() error {
+	var err error
+	if closer, ok := t.w.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if t.onClose != nil {
+		if closeErr := t.onClose(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// --- HTTP Transport ---
+
+// httpTransport implements the client side of the streamable HTTP transport.
+// Every message is POSTed; the response is read as JSON or as a server-sent event stream.
+type httpTransport struct {
+	url    string
+	client *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func (t *httpTransport) call(ctx context.Context, req message) (message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return message{}, err
+	}
+	defer resp.Body.Close()
+
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readEventStream(resp.Body, req.ID)
+	}
+	var msg message
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMessageSize)).Decode(&msg); err != nil {
+		return message{}, fmt.Errorf("error decoding MCP response: %w", err)
+	}
+	return msg, nil
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+// post sends a message and checks the HTTP status.
+func (t *httpTransport) post(ctx context.Context, msg message) (*http.Response, error) {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling MCP message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error creating MCP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setSessionHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending MCP request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("MCP server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// setSessionHeaders adds the session and protocol version headers, once known.
+func (t *httpTransport) setSessionHeaders(req *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+}
+
+// close terminates the session, if the server assigned one.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setSessionHeaders(req)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error closing MCP session: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// readEventStream reads server-sent events until the response to the request with the given ID.
+func readEventStream(r io.Reader, id json.RawMessage) (message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	var data strings.Builder
+	dataLines := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			// The data lines of an event are joined with newlines
+			if dataLines > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			dataLines++
+			continue
+		}
+		if line != "" || dataLines == 0 {
+			continue
+		}
+		// A blank line ends the event
+		var msg message
+		if err := json.Unmarshal([]byte(data.String()), &msg); err == nil && msg.Method == "" && string(msg.ID) == string(id) {
+			return msg, nil
+		}
+		data.Reset()
+		dataLines = 0
+	}
+	if err := scanner.Err(); err != nil {
+		return message{}, fmt.Errorf("error reading MCP event stream: %w", err)
+	}
+	return message{}, fmt.Errorf("MCP event stream ended without a response")
+}
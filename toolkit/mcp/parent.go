@@ -0,0 +1,129 @@
+// Package mcp connects toolkits to the Model Context Protocol (MCP).
+// This file bridges remote MCP tools into a toolkit: every tool of a server becomes a
+// Child of a Parent, so native Go tools and existing MCP servers mix in one toolkit.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+)
+
+// NewParent lists the tools of an MCP server and builds a Parent with one Child per tool.
+// The children forward Handle calls to `tools/call` on the client's server and report
+// the server-provided input and output schemas from GetInputSchema and GetOutputSchema.
+// The parent is a regular toolkit parent, so execution modes, timeouts and options apply as usual.
+//
+// The client must stay open while the toolkit is in use; the caller closes it.
+//
+// Parameters:
+//   - ctx: Context for the `tools/list` request
+//   - client: A connected MCP client (see DialCommand, DialHTTP, NewStdioClient)
+//   - name: The name of the parent in the toolkit
+//   - description: A description of the tools provided by the server
+//   - opts: Parent options such as toolkit.WithParentTimeout
+//
+// Example:
+//
+//	client, err := mcp.DialCommand(ctx, "my-mcp-server")
+//	if err != nil { ... }
+//	defer client.Close()
+//	remote, err := mcp.NewParent(ctx, client, "remote", "Tools of my MCP server")
+//	if err != nil { ... }
+//	tk := toolkit.New("my_toolkit", fileOpsParent, remote)
+func NewParent(ctx context.Context, client *Client, name, description string, opts ...toolkit.ParentOption) (toolkit.Parent, error) {
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tools of MCP server '%s': %w", client.ServerInfo().Name, err)
+	}
+	children := make([]toolkit.Child, 0, len(tools))
+	for _, tool := range tools {
+		children = append(children, &remoteChild{client: client, tool: tool})
+	}
+	return toolkit.NewParentWithOptions(name, description, opts, children...), nil
+}
+
+// remoteChild implements toolkit.Child by forwarding calls to a remote MCP tool.
+type remoteChild struct {
+	client *Client
+	tool   Tool
+}
+
+// GetName returns the name of the remote tool.
+func (c *remoteChild) GetName() string {
+	return c.tool.Name
+}
+
+// GetDescription returns the description of the remote tool.
+func (c *remoteChild) GetDescription() string {
+	return c.tool.Description
+}
+
+// GetInputSchema returns the input schema provided by the server, as raw JSON.
+func (c *remoteChild) GetInputSchema() interface{} {
+	return c.tool.InputSchema
+}
+
+// GetOutputSchema implements toolkit.TypedChild by returning the output schema provided
+// by the server, as raw JSON, or nil if the tool does not declare one.
+func (c *remoteChild) GetOutputSchema() interface{} {
+	if len(c.tool.OutputSchema) == 0 {
+		return nil
+	}
+	return c.tool.OutputSchema
+}
+
+// Handle calls the remote tool. Results are returned as raw JSON when the server
+// returns structured or JSON text content, as a string for plain text, and as the
+// content blocks otherwise. Failed calls are returned as ToolKitErrors; errors sent
+// by a toolkit-backed MCP server keep their original code.
+func (c *remoteChild) Handle(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	if len(args) == 0 {
+		args = json.RawMessage(`{}`)
+	}
+	result, err := c.client.CallTool(ctx, c.tool.Name, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, toolkit.NewError(toolkit.CodeHandlerExecutionError, fmt.Sprintf("Error calling MCP tool '%s': %v", c.tool.Name, err))
+	}
+
+	if result.IsError {
+		text := contentText(result.Content)
+		var tkErr toolkit.ToolKitError
+		if json.Unmarshal([]byte(text), &tkErr) == nil && tkErr.Code != "" {
+			return nil, tkErr
+		}
+		return nil, toolkit.NewError(toolkit.CodeHandlerExecutionError, fmt.Sprintf("MCP tool '%s' failed: %s", c.tool.Name, text))
+	}
+
+	if len(result.StructuredContent) > 0 {
+		return result.StructuredContent, nil
+	}
+	if len(result.Content) == 1 && result.Content[0].Type == "text" {
+		text := result.Content[0].Text
+		if json.Valid([]byte(text)) {
+			return json.RawMessage(text), nil
+		}
+		return text, nil
+	}
+	return result.Content, nil
+}
+
+// contentText joins the text blocks of a tool result.
+func contentText(content []Content) string {
+	text := ""
+	for _, block := range content {
+		if block.Type != "text" {
+			continue
+		}
+		if text != "" {
+			text += "\n"
+		}
+		text += block.Text
+	}
+	return text
+}
@@ -0,0 +1,144 @@
+// Package mcp connects toolkits to the Model Context Protocol (MCP).
+// This file defines the JSON-RPC 2.0 envelope and the subset of MCP messages used
+// by the server adapter: initialization, ping, tools/list and tools/call.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LatestProtocolVersion is the newest MCP protocol revision supported by this package.
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions lists the MCP revisions this package can speak, newest first.
+var supportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+const jsonrpcVersion = "2.0"
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// --- JSON-RPC Envelope ---
+
+// message is a JSON-RPC 2.0 request, notification or response.
+// Requests carry an ID and a Method, notifications only a Method,
+// and responses an ID with either a Result or an Error.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// isNotification reports whether the message is a notification, which never gets a response.
+func (m message) isNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+// RPCError is a JSON-RPC 2.0 error object. It is returned for protocol failures such as
+// unknown methods or tools; failures of the tools themselves are reported as a
+// CallToolResult with IsError set instead.
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements the standard error interface for RPCError.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: %s (code %d)", e.Message, e.Code)
+}
+
+// newRPCError creates an RPCError with a formatted message.
+func newRPCError(code int, format string, args ...interface{}) *RPCError {
+	return &RPCError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// --- MCP Messages ---
+
+// Implementation identifies an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// initializeParams are the parameters of the `initialize` request.
+type initializeParams struct {
+	ProtocolVersion string          `json:"protocolVersion"`
+	Capabilities    json.RawMessage `json:"capabilities"`
+	ClientInfo      Implementation  `json:"clientInfo"`
+}
+
+// initializeResult is the result of the `initialize` request.
+type initializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    serverCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
+}
+
+// serverCapabilities advertises the MCP features of a server.
+type serverCapabilities struct {
+	Tools *toolsCapability `json:"tools,omitempty"`
+}
+
+// toolsCapability advertises tool support.
+type toolsCapability struct {
+	ListChanged bool `json:"listChanged"`
+}
+
+// Tool describes a tool in a `tools/list` result.
+type Tool struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"inputSchema"`
+	OutputSchema json.RawMessage `json:"outputSchema,omitempty"` // Schema of the structured content of results
+}
+
+// listToolsParams are the parameters of the `tools/list` request.
+type listToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// listToolsResult is the result of the `tools/list` request.
+type listToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// callToolParams are the parameters of the `tools/call` request.
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Content is a content block of a tool result. Toolkit results are always "text"
+// blocks holding JSON; other block types are preserved as received from remote servers.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// CallToolResult is the result of the `tools/call` request.
+type CallToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// cancelledParams are the parameters of the `notifications/cancelled` notification.
+type cancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
@@ -0,0 +1,539 @@
+// Package mcp connects toolkits to the Model Context Protocol (MCP).
+// This file implements the server adapter, which serves a *toolkit.Toolkit to any MCP
+// client over stdio or streamable HTTP without a separate server implementation.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+)
+
+// maxMessageSize bounds a single JSON-RPC message read from stdio or an HTTP body.
+const maxMessageSize = 16 << 20
+
+// ToolMode selects which tools a Server lists and accepts.
+type ToolMode int
+
+const (
+	// ToolModeAll exposes both the hierarchical toolkit tool and every flat child tool.
+	ToolModeAll ToolMode = iota
+	// ToolModeHierarchical exposes only the toolkit tool, dispatched through HandleToolKit.
+	ToolModeHierarchical
+	// ToolModeFlat exposes only the flat `parent__child` tools, dispatched through HandleFlatTool.
+	ToolModeFlat
+)
+
+// Server serves a Toolkit as an MCP server. Its tools are generated from the toolkit's
+// parents and children:
+//   - the toolkit itself, named after the toolkit, takes a ToolKit request as arguments
+//     and is executed with HandleToolKit (see toolkit.Toolkit.GenerateToolkitSchema)
+//   - every child, named `parent__child`, takes the child's arguments and is executed
+//     with HandleFlatTool (see toolkit.Toolkit.GetFlatTools)
+//
+// Failed tools are reported as tool results with `isError` set and the ToolKitError as
+// JSON text, so the model can read and correct the error. Toolkit calls in which any child
+// failed are flagged with `isError` too, and still carry the results of every child. Unknown tools and malformed
+// requests are reported as JSON-RPC errors.
+//
+// A Server is safe for concurrent use; Serve and ServeHTTP may run at the same time.
+type Server struct {
+	toolkit        *toolkit.Toolkit
+	info           Implementation
+	mode           ToolMode
+	allowedOrigins map[string]bool
+}
+
+// ServerOption configures a Server created with NewServer.
+type ServerOption func(*Server)
+
+// WithServerInfo sets the name and version the server reports during initialization.
+// The default is the toolkit name and version "0.1.0".
+func WithServerInfo(name, version string) ServerOption {
+	return func(s *Server) {
+		s.info = Implementation{Name: name, Version: version}
+	}
+}
+
+// WithToolMode selects which tools the server exposes. The default is ToolModeAll.
+func WithToolMode(mode ToolMode) ServerOption {
+	return func(s *Server) {
+		s.mode = mode
+	}
+}
+
+// WithAllowedOrigins lists the browser origins (e.g., "http://localhost:3000") allowed to
+// call the HTTP transport. Requests without an Origin header, as sent by non-browser
+// clients, are always accepted; requests from other origins are rejected to prevent
+// DNS rebinding attacks.
+func WithAllowedOrigins(origins ...string) ServerOption {
+	return func(s *Server) {
+		for _, origin := range origins {
+			s.allowedOrigins[origin] = true
+		}
+	}
+}
+
+// NewServer creates an MCP server for the given toolkit.
+//
+// Example:
+//
+//	server := mcp.NewServer(myToolkit, mcp.WithToolMode(mcp.ToolModeFlat))
+//	if err := server.ServeStdio(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+func NewServer(tk *toolkit.Toolkit, opts ...ServerOption) *Server {
+	s := &Server{
+		toolkit:        tk,
+		info:           Implementation{Name: tk.GetToolkitName(), Version: "0.1.0"},
+		mode:           ToolModeAll,
+		allowedOrigins: map[string]bool{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
+
+// --- Transports ---
+
+// ServeStdio serves MCP over the process's standard input and output.
+// It returns when standard input is closed.
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// Serve serves MCP over newline-delimited JSON-RPC messages read from r, writing
+// responses to w. Requests run concurrently, so a long tool call does not block pings
+// or other calls; `notifications/cancelled` cancels the context of a running request.
+// Changes of the toolkit's parents and children (see toolkit.Toolkit.OnChange) are sent
+// to the client as `notifications/tools/list_changed`.
+// Serve returns when r reaches EOF, after all running requests have been answered; it
+// does not write to w after it returned.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(withListChanged(ctx))
+	defer cancel()
+
+	var (
+		writeMu  sync.Mutex
+		wg       sync.WaitGroup
+		inflight = newInflightRequests()
+	)
+	write := func(data []byte) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			s.logger().Warn("MCP server: error writing response", toolkit.LogKeyError, err.Error())
+		}
+	}
+
+	// Changes are coalesced and sent from a separate goroutine, so a slow client never
+	// blocks the code changing the toolkit. The goroutine writes to w, so Serve waits
+	// for it before returning.
+	changed := make(chan struct{}, 1)
+	stopListening := s.toolkit.OnChange(func(toolkit.RegistryChange) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	notifierDone := make(chan struct{})
+	defer func() {
+		stopListening()
+		cancel()
+		<-notifierDone
+	}()
+	go func() {
+		defer close(notifierDone)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				write(encode(message{JSONRPC: jsonrpcVersion, Method: "notifications/tools/list_changed"}))
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		data := append([]byte(nil), line...)
+
+		reqCtx, done := ctx, func() {}
+		var msg message
+		if json.Unmarshal(data, &msg) == nil {
+			if msg.Method == "notifications/cancelled" {
+				var params cancelledParams
+				if json.Unmarshal(msg.Params, &params) == nil {
+					inflight.cancel(params.RequestID)
+				}
+				continue
+			}
+			if len(msg.ID) > 0 && msg.Method != "" {
+				reqCtx, done = inflight.start(ctx, msg.ID)
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer done()
+			if resp, _ := s.handleRaw(reqCtx, data); resp != nil {
+				write(resp)
+			}
+		}()
+	}
+	wg.Wait()
+	return scanner.Err()
+}
+
+// ServeHTTP implements the MCP streamable HTTP transport on a single endpoint.
+// Each POST carries one JSON-RPC message (or a batch) and is answered with a JSON
+// response; messages without responses are acknowledged with 202 Accepted. The server
+// does not open server-initiated streams, so GET and other methods get 405, and tool
+// list changes are not announced to HTTP clients.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !s.allowedOrigins[origin] {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, malformed := s.handleRaw(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if malformed {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if _, err := w.Write(resp); err != nil {
+		s.logger().Warn("MCP server: error writing HTTP response", toolkit.LogKeyError, err.Error())
+	}
+}
+
+// --- Dispatch ---
+
+// handleRaw handles a single JSON-RPC message or a batch and returns the encoded
+// response, or nil if nothing has to be answered. malformed reports input that is not
+// valid JSON-RPC at all.
+func (s *Server) handleRaw(ctx context.Context, data []byte) (resp []byte, malformed bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return encode(errorResponse(nil, newRPCError(codeParseError, "Parse error: %v", err))), true
+		}
+		if len(batch) == 0 {
+			return encode(errorResponse(nil, newRPCError(codeInvalidRequest, "Invalid request: empty batch"))), true
+		}
+		var responses []*message
+		for _, item := range batch {
+			if r := s.handleMessage(ctx, item); r != nil {
+				responses = append(responses, r)
+			}
+		}
+		if len(responses) == 0 {
+			return nil, false
+		}
+		return encode(responses), false
+	}
+
+	var probe interface{}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return encode(errorResponse(nil, newRPCError(codeParseError, "Parse error: %v", err))), true
+	}
+	r := s.handleMessage(ctx, data)
+	if r == nil {
+		return nil, false
+	}
+	return encode(r), r.Error != nil && len(r.ID) == 0
+}
+
+// handleMessage handles a single JSON-RPC message and returns its response, if any.
+func (s *Server) handleMessage(ctx context.Context, data json.RawMessage) *message {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil || msg.JSONRPC != jsonrpcVersion {
+		return errorResponse(msg.ID, newRPCError(codeInvalidRequest, "Invalid request: expected a JSON-RPC 2.0 message"))
+	}
+	if msg.Method == "" {
+		// Responses to server requests; this server never sends requests
+		return nil
+	}
+
+	result, err := s.dispatch(ctx, msg)
+	if msg.isNotification() {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(msg.ID, err)
+	}
+	raw, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		return errorResponse(msg.ID, newRPCError(codeInternalError, "Internal error: %v", marshalErr))
+	}
+	return &message{JSONRPC: jsonrpcVersion, ID: msg.ID, Result: raw}
+}
+
+// logger returns the logger of the served toolkit.
+func (s *Server) logger() *slog.Logger {
+	return s.toolkit.Logger()
+}
+
+// dispatch routes a request or notification to its MCP method.
+func (s *Server) dispatch(ctx context.Context, msg message) (interface{}, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(ctx, msg.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(msg.Params)
+	case "tools/call":
+		// The toolkit logs the call under the JSON-RPC request ID
+		return s.callTool(toolkit.ContextWithCallID(ctx, strings.Trim(string(msg.ID), `"`)), msg.Params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	default:
+		return nil, newRPCError(codeMethodNotFound, "Method not found: %s", msg.Method)
+	}
+}
+
+// listChangedKey is the context key marking transports that deliver server notifications.
+type listChangedKey struct{}
+
+// withListChanged marks ctx as served by a transport that sends tool list changes.
+func withListChanged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, listChangedKey{}, true)
+}
+
+// initialize negotiates the protocol version and advertises the tools capability.
+// List changes are only advertised on transports that send them.
+func (s *Server) initialize(ctx context.Context, raw json.RawMessage) (interface{}, *RPCError) {
+	var params initializeParams
+	if err := unmarshalParams(raw, &params); err != nil {
+		return nil, err
+	}
+	listChanged, _ := ctx.Value(listChangedKey{}).(bool)
+	version := LatestProtocolVersion
+	for _, supported := range supportedProtocolVersions {
+		if params.ProtocolVersion == supported {
+			version = supported
+		}
+	}
+	return initializeResult{
+		ProtocolVersion: version,
+		Capabilities:    serverCapabilities{Tools: &toolsCapability{ListChanged: listChanged}},
+		ServerInfo:      s.info,
+	}, nil
+}
+
+// listTools returns every tool exposed by the server. All tools fit in one page.
+func (s *Server) listTools(raw json.RawMessage) (interface{}, *RPCError) {
+	var params listToolsParams
+	if err := unmarshalParams(raw, &params); err != nil {
+		return nil, err
+	}
+	tools, err := s.tools()
+	if err != nil {
+		return nil, newRPCError(codeInternalError, "Internal error: %v", err)
+	}
+	return listToolsResult{Tools: tools}, nil
+}
+
+// tools generates the MCP tool list from the toolkit's parents and children.
+func (s *Server) tools() ([]Tool, error) {
+	tools := []Tool{}
+	if s.mode != ToolModeFlat {
+		schema, err := json.Marshal(s.toolkit.GenerateToolkitSchema())
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, Tool{
+			Name:        s.toolkit.GetToolkitName(),
+			Description: s.toolkit.GetToolkitDescription(),
+			InputSchema: schema,
+		})
+	}
+	if s.mode != ToolModeHierarchical {
+		for _, flat := range s.toolkit.GetFlatTools() {
+			schema, err := json.Marshal(flat.InputSchema)
+			if err != nil {
+				return nil, err
+			}
+			tool := Tool{Name: flat.Name, Description: flat.Description, InputSchema: schema}
+			// MCP output schemas describe structuredContent, which is only sent for objects
+			if flat.OutputSchema != nil && flat.OutputSchema.Type == "object" {
+				if tool.OutputSchema, err = json.Marshal(flat.OutputSchema); err != nil {
+					return nil, err
+				}
+			}
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+// callTool executes a tool call through HandleToolKit or HandleFlatTool.
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (interface{}, *RPCError) {
+	var params callToolParams
+	if err := unmarshalParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Name == "" {
+		return nil, newRPCError(codeInvalidParams, "Invalid params: missing tool name")
+	}
+	args := params.Arguments
+	if len(bytes.TrimSpace(args)) == 0 || bytes.Equal(bytes.TrimSpace(args), []byte("null")) {
+		args = json.RawMessage(`{}`)
+	}
+
+	if s.mode != ToolModeFlat && params.Name == s.toolkit.GetToolkitName() {
+		resp, err := s.toolkit.HandleToolKit(ctx, args)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		// The response still carries the results of the successful children
+		result := jsonResult(resp)
+		result.IsError = result.IsError || resp.HasErrors()
+		return result, nil
+	}
+
+	if s.mode != ToolModeHierarchical {
+		resp, err := s.toolkit.HandleFlatTool(ctx, params.Name, args)
+		var tkErr toolkit.ToolKitError
+		if errors.As(err, &tkErr) && tkErr.Code == toolkit.CodeToolNotFound {
+			return nil, newRPCError(codeInvalidParams, "Unknown tool: %s", params.Name)
+		}
+		if err != nil {
+			return errorResult(err), nil
+		}
+		if resp.Failed() {
+			return errorResult(resp.Err()), nil
+		}
+		return jsonResult(resp.Result), nil
+	}
+
+	return nil, newRPCError(codeInvalidParams, "Unknown tool: %s", params.Name)
+}
+
+// --- Helpers ---
+
+// jsonResult encodes a tool result as a JSON text block. Object results are also
+// returned as structured content.
+func jsonResult(v interface{}) CallToolResult {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errorResult(toolkit.NewError(toolkit.CodeHandlerExecutionError, "Error marshaling tool result: "+err.Error()))
+	}
+	result := CallToolResult{Content: []Content{{Type: "text", Text: string(raw)}}}
+	if len(raw) > 0 && raw[0] == '{' {
+		result.StructuredContent = raw
+	}
+	return result
+}
+
+// errorResult encodes a failed tool call. ToolKitErrors are sent as JSON so the model
+// sees the error code, message and any argument violations.
+func errorResult(err error) CallToolResult {
+	text := err.Error()
+	var tkErr toolkit.ToolKitError
+	if errors.As(err, &tkErr) {
+		if raw, marshalErr := json.Marshal(tkErr); marshalErr == nil {
+			text = string(raw)
+		}
+	}
+	return CallToolResult{Content: []Content{{Type: "text", Text: text}}, IsError: true}
+}
+
+// unmarshalParams decodes request params; absent params leave v unchanged.
+func unmarshalParams(raw json.RawMessage, v interface{}) *RPCError {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return newRPCError(codeInvalidParams, "Invalid params: %v", err)
+	}
+	return nil
+}
+
+// errorResponse builds a JSON-RPC error response. Errors that cannot be tied to a
+// request use a null ID, as required by JSON-RPC.
+func errorResponse(id json.RawMessage, err *RPCError) *message {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &message{JSONRPC: jsonrpcVersion, ID: id, Error: err}
+}
+
+// encode marshals a response; message values always encode.
+func encode(v interface{}) []byte {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		slog.Default().Error("MCP server: error encoding response", toolkit.LogKeyError, err.Error())
+		return nil
+	}
+	return raw
+}
+
+// inflightRequests tracks the cancel functions of running stdio requests by ID.
+type inflightRequests struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newInflightRequests() *inflightRequests {
+	return &inflightRequests{cancels: map[string]context.CancelFunc{}}
+}
+
+// start derives a cancelable context for a request; done must be called when it finishes.
+func (r *inflightRequests) start(ctx context.Context, id json.RawMessage) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(id)
+	r.mu.Lock()
+	r.cancels[key] = cancel
+	r.mu.Unlock()
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.cancels, key)
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+// cancel cancels the running request with the given ID, if any.
+func (r *inflightRequests) cancel(id json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.cancels[string(id)]; ok {
+		cancel()
+	}
+}
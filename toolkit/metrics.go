@@ -0,0 +1,74 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file defines the metrics hook, which receives one observation per child invocation so
+// call counts, latencies, payload sizes and error codes can be exported to a metrics backend.
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ChildObservation is the measurement of a single child invocation.
+//
+// Requests for unknown tools are observed too, with the ErrorCode "parent_not_found" (Child
+// is empty), "child_not_found" or, for HandleFlatTool, "tool_not_found" (Parent is empty and
+// Child is the flat tool name). Their names come from the model and are unbounded, so
+// backends should not use them as labels; a rising count shows the model hallucinating tools.
+type ChildObservation struct {
+	Toolkit    string        // Toolkit name
+	Parent     string        // Parent name
+	Child      string        // Child name
+	Duration   time.Duration // Execution time of the child, including middleware
+	ArgsSize   int           // Size of the raw arguments in bytes
+	ResultSize int           // Size of the JSON encoded result in bytes; 0 for failures
+	ErrorCode  string        // ToolKitError code of a failure; empty on success
+}
+
+// Metrics receives an observation for every child invocation executed by the built-in parents,
+// and for every request naming an unknown parent, child or flat tool. Implementations must be safe for
+// concurrent use and should return quickly, since they run on the execution path.
+//
+// The toolkit/prometheus package provides a Prometheus implementation.
+type Metrics interface {
+	ObserveChild(obs ChildObservation)
+}
+
+// MetricsFunc adapts a function to the Metrics interface.
+type MetricsFunc func(obs ChildObservation)
+
+// ObserveChild implements the Metrics interface by calling f.
+func (f MetricsFunc) ObserveChild(obs ChildObservation) {
+	f(obs)
+}
+
+// observeChild reports an observation to the metrics of the execution running under ctx.
+func observeChild(ctx context.Context, obs ChildObservation) {
+	e := executionFrom(ctx)
+	if e.metrics == nil {
+		return
+	}
+	obs.Toolkit = e.toolkit
+	e.metrics.ObserveChild(obs)
+}
+
+// errorCode returns the ToolKitError code of a child response, or "" if it succeeded.
+func errorCode(resp ChildResponse) string {
+	if resp.Error == nil {
+		return ""
+	}
+	return resp.Error.Code
+}
+
+// responseSize returns the size of the JSON encoded result of a successful child response,
+// or 0 for failures and results that cannot be encoded.
+func responseSize(resp ChildResponse) int {
+	if resp.Failed() {
+		return 0
+	}
+	raw, err := json.Marshal(resp.Result)
+	if err != nil {
+		return 0
+	}
+	return len(raw)
+}
@@ -0,0 +1,115 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file defines the middleware chain wrapped around child execution, which makes
+// cross-cutting concerns such as logging, auth, caching and metrics pluggable.
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ChildCall describes a single child invocation seen by middleware.
+type ChildCall struct {
+	Parent string          // Name of the parent executing the child
+	Child  string          // Name of the child
+	Args   json.RawMessage // Raw arguments of the invocation
+}
+
+// Handler executes a child invocation and returns its result.
+type Handler func(ctx context.Context, call ChildCall) (interface{}, error)
+
+// Middleware wraps a Handler with additional behavior. A middleware may inspect or
+// replace the call before passing it to next, short-circuit by returning without calling
+// next, or inspect and replace the result.
+//
+// Middleware is registered with WithMiddleware (toolkit), WithParentMiddleware (parent),
+// WithChildMiddleware (children created with NewChild) or WrapChild (any Child). It runs
+// whenever a built-in parent executes a child, around the child's Handle method, from the
+// outermost toolkit middleware to the innermost child middleware; within a level, the
+// first registered middleware is the outermost.
+//
+// Example:
+//
+//	logging := func(next toolkit.Handler) toolkit.Handler {
+//	    return func(ctx context.Context, call toolkit.ChildCall) (interface{}, error) {
+//	        start := time.Now()
+//	        result, err := next(ctx, call)
+//	        log.Printf("%s.%s took %s (error: %v)", call.Parent, call.Child, time.Since(start), err)
+//	        return result, err
+//	    }
+//	}
+//	tk := toolkit.NewWithOptions("my_toolkit", []toolkit.Option{toolkit.WithMiddleware(logging)}, parents...)
+type Middleware func(next Handler) Handler
+
+// chainMiddleware wraps handler with the given middleware, the first being the outermost.
+func chainMiddleware(handler Handler, middleware ...[]Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		for j := len(middleware[i]) - 1; j >= 0; j-- {
+			if mw := middleware[i][j]; mw != nil {
+				handler = mw(handler)
+			}
+		}
+	}
+	return handler
+}
+
+// childMiddleware returns the child-level middleware of a child, if any.
+func childMiddleware(child Child) []Middleware {
+	if c, ok := child.(interface{ middleware() []Middleware }); ok {
+		return c.middleware()
+	}
+	return nil
+}
+
+// middleware returns the middleware registered with WithChildMiddleware.
+func (c *internalChild[ArgsT]) middleware() []Middleware {
+	return c.childConfig.middleware
+}
+
+// --- Wrapped Children ---
+
+// WrapChild returns a Child that behaves like child and runs the given middleware around
+// it, for custom Child implementations that can't use WithChildMiddleware. Rollback
+// support of the wrapped child is preserved.
+//
+// Example:
+//
+//	secured := toolkit.WrapChild(myCustomChild, requireAuth)
+//	parent := toolkit.NewParent("admin", "Administrative tools", secured)
+func WrapChild(child Child, middleware ...Middleware) Child {
+	return &wrappedChild{Child: child, mw: append(append([]Middleware(nil), childMiddleware(child)...), middleware...)}
+}
+
+// wrappedChild adds child-level middleware to any Child.
+type wrappedChild struct {
+	Child
+	mw []Middleware
+}
+
+// middleware returns the child-level middleware of the wrapped child.
+func (c *wrappedChild) middleware() []Middleware {
+	return c.mw
+}
+
+// Rollback implements the Rollbacker interface by delegating to the wrapped child.
+func (c *wrappedChild) Rollback(ctx context.Context, args json.RawMessage, result interface{}) error {
+	if r := rollbackerOf(c.Child); r != nil {
+		return r.Rollback(ctx, args, result)
+	}
+	return nil
+}
+
+// GetOutputSchema implements the TypedChild interface by delegating to the wrapped child.
+func (c *wrappedChild) GetOutputSchema() interface{} {
+	return OutputSchemaOf(c.Child)
+}
+
+// enforcesDeadline reports whether the wrapped child applies its own timeout.
+func (c *wrappedChild) enforcesDeadline() bool {
+	return enforcesDeadline(c.Child)
+}
+
+// hasRollback reports whether the wrapped child can be rolled back.
+func (c *wrappedChild) hasRollback() bool {
+	return rollbackerOf(c.Child) != nil
+}
@@ -0,0 +1,221 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file defines the functional options used to configure Toolkit, Parent and Child
+// instances at construction time.
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// --- Toolkit Options ---
+
+// Option configures a Toolkit instance created with NewWithOptions.
+type Option func(*Toolkit)
+
+// WithExecutionMode sets how the toolkit executes the parents and children of a request.
+// The default is ExecutionSequential, which preserves the historical one-at-a-time behavior.
+func WithExecutionMode(mode ExecutionMode) Option {
+	return func(t *Toolkit) {
+		t.mode = mode
+	}
+}
+
+// WithMaxConcurrency limits how many child tools may run at the same time within a
+// single HandleToolKit call when the toolkit uses ExecutionConcurrent.
+// A limit of zero or less means no limit. A child whose handler was abandoned after a
+// timeout keeps its slot until the handler returns, so the limit bounds the handlers that
+// are actually running.
+func WithMaxConcurrency(limit int) Option {
+	return func(t *Toolkit) {
+		t.maxConcurrency = limit
+	}
+}
+
+// WithTimeout bounds the total duration of a single HandleToolKit call.
+// Children still running when the timeout expires report a "timeout" error,
+// while children that already finished keep their results. Zero disables the timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(t *Toolkit) {
+		t.timeout = d
+	}
+}
+
+// WithExecutionPolicy sets what happens to the rest of a request once a child fails.
+// The default is PolicyContinue. RequestOptions.Policy overrides it for a single request.
+func WithExecutionPolicy(policy ExecutionPolicy) Option {
+	return func(t *Toolkit) {
+		t.policy = policy
+	}
+}
+
+// WithMiddleware adds middleware that runs around every child executed by the toolkit's
+// built-in parents (see Middleware). It runs outside parent and child middleware.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(t *Toolkit) {
+		t.middleware = append(t.middleware, middleware...)
+	}
+}
+
+// WithLogger sets the logger the toolkit writes to; the default is slog.Default().
+// Records carry the attributes described by the LogKey constants; arguments are never
+// logged, only their size (see RedactLogAttrs). A nil logger silences the toolkit.
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *Toolkit) {
+		if logger == nil {
+			logger = discardLogger()
+		}
+		t.logger = logger
+	}
+}
+
+// WithTracer sets the tracer of the toolkit, such as the OpenTelemetry tracer of the
+// toolkit/otel package. Every request produces a toolkit span with parent and child spans
+// below it, started as a child of the span in the request context.
+func WithTracer(tracer Tracer) Option {
+	return func(t *Toolkit) {
+		t.tracer = tracer
+	}
+}
+
+// WithMetrics sets the metrics hook that observes every child invocation of the toolkit,
+// such as the collector of the toolkit/prometheus package.
+func WithMetrics(metrics Metrics) Option {
+	return func(t *Toolkit) {
+		t.metrics = metrics
+	}
+}
+
+// WithDescriptionBudget limits the size of GetToolkitDescription. Descriptions exceeding the
+// budget are shortened by simplifying the child schemas (see DescriptionBudget).
+//
+// Example:
+//
+//	tk := toolkit.NewWithOptions("my_toolkit", []toolkit.Option{
+//	    toolkit.WithDescriptionBudget(toolkit.DescriptionBudget{MaxTokens: 4000}),
+//	}, parents...)
+func WithDescriptionBudget(budget DescriptionBudget) Option {
+	return func(t *Toolkit) {
+		t.descriptionBudget = budget
+	}
+}
+
+// --- Parent Options ---
+
+// ParentOption configures a Parent created with NewParentWithOptions.
+type ParentOption func(*parentConfig)
+
+// parentConfig holds the optional settings of an internalParent.
+type parentConfig struct {
+	timeout    time.Duration // Bound for one HandleChildren call; zero means none
+	middleware []Middleware  // Middleware run around every child of the parent
+	logger     *slog.Logger  // Logger used outside of a toolkit request; nil means slog.Default()
+}
+
+// WithParentTimeout bounds the duration of a single HandleChildren call on the parent.
+// Children still running when the timeout expires report a "timeout" error.
+// Zero disables the timeout.
+func WithParentTimeout(d time.Duration) ParentOption {
+	return func(c *parentConfig) {
+		c.timeout = d
+	}
+}
+
+// WithParentMiddleware adds middleware that runs around every child of the parent
+// (see Middleware). It runs inside toolkit middleware and outside child middleware.
+func WithParentMiddleware(middleware ...Middleware) ParentOption {
+	return func(c *parentConfig) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithParentLogger sets the logger the parent uses for construction warnings and when its
+// HandleChildren is called outside of a toolkit request. Within a request, the toolkit
+// logger (see WithLogger) is used. A nil logger silences the parent.
+func WithParentLogger(logger *slog.Logger) ParentOption {
+	return func(c *parentConfig) {
+		if logger == nil {
+			logger = discardLogger()
+		}
+		c.logger = logger
+	}
+}
+
+// --- Child Options ---
+
+// ChildOption configures a Child created with NewChild.
+type ChildOption func(*childConfig)
+
+// childConfig holds the optional settings of an internalChild.
+// It is kept separate from the generic internalChild so options stay non-generic.
+type childConfig struct {
+	timeout    time.Duration                                                             // Bound for one Handle call; zero means none
+	rollback   func(ctx context.Context, args json.RawMessage, result interface{}) error // Compensating action; nil means none
+	middleware []Middleware                                                              // Middleware run around the child when a parent executes it
+	retry      *RetryPolicy                                                              // Retries of failed invocations; nil means none
+}
+
+// WithChildTimeout bounds the duration of a single invocation of the child.
+// When the timeout expires, Handle returns a "timeout" error without waiting
+// for the handler to return. Zero disables the timeout.
+func WithChildTimeout(d time.Duration) ChildOption {
+	return func(c *childConfig) {
+		c.timeout = d
+	}
+}
+
+// WithRetry retries failed invocations of the child according to the policy (see RetryPolicy).
+// Every attempt is bounded by the child timeout; the number of attempts is reported in
+// ChildResponse.Attempts. Custom Child implementations get retries with RetryMiddleware.
+//
+// Example:
+//
+//	fetch := toolkit.NewChild("fetch_url", "Fetches a URL", fetchHandler,
+//	    toolkit.WithChildTimeout(10*time.Second),
+//	    toolkit.WithRetry(toolkit.RetryPolicy{
+//	        MaxAttempts: 3,
+//	        RetryIf:     toolkit.RetryOnCodes(toolkit.CodeHandlerExecutionError),
+//	    }))
+func WithRetry(policy RetryPolicy) ChildOption {
+	return func(c *childConfig) {
+		c.retry = &policy
+	}
+}
+
+// WithChildMiddleware adds middleware that runs around the child when a parent executes it
+// (see Middleware). It runs inside toolkit and parent middleware. Custom Child
+// implementations get child-level middleware with WrapChild.
+func WithChildMiddleware(middleware ...Middleware) ChildOption {
+	return func(c *childConfig) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithRollback makes the child a Rollbacker: under PolicyAllOrNothing, fn is called with the
+// arguments and the result of every successful execution of the child when another child of
+// the same request fails. ArgsT must be the argument type of the child's handler.
+//
+// Example:
+//
+//	writeFile := toolkit.NewChild("write_file", "Writes a file", writeHandler,
+//	    toolkit.WithRollback(func(ctx context.Context, args WriteArgs, result interface{}) error {
+//	        return os.Remove(args.Path)
+//	    }))
+func WithRollback[ArgsT any](fn func(ctx context.Context, args ArgsT, result interface{}) error) ChildOption {
+	return func(c *childConfig) {
+		if fn == nil {
+			c.rollback = nil
+			return
+		}
+		c.rollback = func(ctx context.Context, args json.RawMessage, result interface{}) error {
+			var typedArgs ArgsT
+			if err := json.Unmarshal(normalizeArgs(args), &typedArgs); err != nil {
+				return fmt.Errorf("error unmarshaling rollback arguments: %w", err)
+			}
+			return fn(ctx, typedArgs, result)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package otel exports toolkit traces to OpenTelemetry.
+// This file implements Tracer, a toolkit.Tracer hook that records the span tree of every
+// request (toolkit, parents, children) with an OpenTelemetry tracer provider.
+package otel
+
+import (
+	"context"
+	"errors"
+
+	gotel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+)
+
+// TracerName is the instrumentation scope of the toolkit spans.
+const TracerName = "github.com/h-ess/ai-toolkit/toolkit"
+
+// Span attributes set by the tracer.
+const (
+	AttrToolkitName   = attribute.Key("toolkit.name")
+	AttrCallID        = attribute.Key("toolkit.call_id")
+	AttrParentName    = attribute.Key("toolkit.parent.name")
+	AttrChildName     = attribute.Key("toolkit.child.name")
+	AttrArgsSize      = attribute.Key("toolkit.child.args_size")
+	AttrResultSize    = attribute.Key("toolkit.child.result_size")
+	AttrAttempts      = attribute.Key("toolkit.child.attempts")
+	AttrErrorCode     = attribute.Key("toolkit.error.code")
+	AttrChildCount    = attribute.Key("toolkit.children.count")
+	AttrFailedCount   = attribute.Key("toolkit.children.failed")
+	AttrToolkitStatus = attribute.Key("toolkit.status")
+)
+
+// Tracer records the spans of one or more toolkits with OpenTelemetry:
+//
+//   - "toolkit <name>" for every HandleToolKit or HandleFlatTool call
+//   - "parent <name>" for every requested parent, below the toolkit span
+//   - "child <parent>.<child>" for every child execution, below its parent span
+//
+// Child spans carry the argument and result sizes, the toolkit.error.code of failures and a
+// toolkit.status attribute. A Tracer is safe for concurrent use. Pass it to the toolkits with
+// toolkit.WithTracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// tracerConfig holds the settings of a Tracer under construction.
+type tracerConfig struct {
+	provider trace.TracerProvider
+}
+
+// TracerOption configures a Tracer created with NewTracer.
+type TracerOption func(*tracerConfig)
+
+// WithTracerProvider sets the tracer provider of the spans. The default is the global
+// provider (otel.GetTracerProvider).
+func WithTracerProvider(provider trace.TracerProvider) TracerOption {
+	return func(c *tracerConfig) {
+		c.provider = provider
+	}
+}
+
+// NewTracer creates a Tracer.
+//
+// Example:
+//
+//	myToolkit := toolkit.NewWithOptions("my_app_toolkit", []toolkit.Option{
+//	    toolkit.WithTracer(otel.NewTracer(otel.WithTracerProvider(tracerProvider))),
+//	}, fileOpsParent)
+func NewTracer(opts ...TracerOption) *Tracer {
+	cfg := tracerConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if cfg.provider == nil {
+		return &Tracer{tracer: gotel.Tracer(TracerName)}
+	}
+	return &Tracer{tracer: cfg.provider.Tracer(TracerName)}
+}
+
+// StartSpan implements the toolkit.Tracer interface.
+func (t *Tracer) StartSpan(ctx context.Context, start toolkit.SpanStart) (context.Context, toolkit.Span) {
+	var name string
+	var attrs []attribute.KeyValue
+	switch start.Kind {
+	case toolkit.SpanToolkit:
+		name = "toolkit " + start.Toolkit
+		attrs = []attribute.KeyValue{AttrToolkitName.String(start.Toolkit), AttrCallID.String(start.CallID)}
+	case toolkit.SpanParent:
+		name = "parent " + start.Parent
+		attrs = []attribute.KeyValue{AttrParentName.String(start.Parent), AttrChildCount.Int(start.Children)}
+	default:
+		name = "child " + start.Parent + "." + start.Child
+		attrs = []attribute.KeyValue{
+			AttrParentName.String(start.Parent),
+			AttrChildName.String(start.Child),
+			AttrArgsSize.Int(start.ArgsSize),
+		}
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, &otelSpan{span: span, kind: start.Kind}
+}
+
+// otelSpan adapts an OpenTelemetry span to the toolkit.Span interface.
+type otelSpan struct {
+	span trace.Span
+	kind toolkit.SpanKind
+}
+
+// IsRecording implements the toolkit.Span interface.
+func (s *otelSpan) IsRecording() bool {
+	return s.span.IsRecording()
+}
+
+// End implements the toolkit.Span interface.
+func (s *otelSpan) End(end toolkit.SpanEnd) {
+	switch s.kind {
+	case toolkit.SpanToolkit:
+		s.span.SetAttributes(AttrChildCount.Int(end.Children), AttrFailedCount.Int(end.Failed))
+	case toolkit.SpanParent:
+		s.span.SetAttributes(AttrFailedCount.Int(end.Failed))
+	default:
+		if end.Attempts > 0 {
+			s.span.SetAttributes(AttrAttempts.Int(end.Attempts))
+		}
+		if end.Err == nil {
+			s.span.SetAttributes(AttrToolkitStatus.String("ok"))
+			if end.ResultSize >= 0 {
+				s.span.SetAttributes(AttrResultSize.Int(end.ResultSize))
+			}
+		}
+	}
+	if end.Err != nil {
+		recordError(s.span, end.Err)
+	}
+	s.span.End()
+}
+
+// recordError marks a span as failed with the error and its ToolKitError code.
+func recordError(span trace.Span, err error) {
+	var tkErr toolkit.ToolKitError
+	if errors.As(err, &tkErr) {
+		span.SetAttributes(AttrErrorCode.String(tkErr.Code))
+	}
+	span.SetAttributes(AttrToolkitStatus.String("error"))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,158 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file implements execution policies, which decide what happens to the rest of a request
+// once a child fails, and the compensating Rollbacker interface used by all-or-nothing requests.
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ExecutionPolicy controls how a request continues after one of its children fails.
+type ExecutionPolicy string
+
+const (
+	// PolicyContinue runs every child regardless of earlier failures. This is the default.
+	PolicyContinue ExecutionPolicy = "continue"
+
+	// PolicyStopOnFirstError stops starting children once a child has failed. Children that
+	// were not started report a "skipped" error; children already running finish normally.
+	PolicyStopOnFirstError ExecutionPolicy = "stop_on_first_error"
+
+	// PolicyAllOrNothing stops like PolicyStopOnFirstError and additionally rolls back every
+	// child that succeeded and implements Rollbacker, in reverse completion order.
+	PolicyAllOrNothing ExecutionPolicy = "all_or_nothing"
+)
+
+// valid reports whether the policy is known. The empty policy means PolicyContinue.
+func (p ExecutionPolicy) valid() bool {
+	switch p {
+	case "", PolicyContinue, PolicyStopOnFirstError, PolicyAllOrNothing:
+		return true
+	}
+	return false
+}
+
+// Rollbacker is implemented by children whose effects can be undone. Under PolicyAllOrNothing,
+// Rollback is called for every successful execution of the child when another child of the
+// same request fails. It receives the arguments and the result of that execution.
+//
+// Children created with NewChild implement it when the WithRollback option is used.
+type Rollbacker interface {
+	Rollback(ctx context.Context, args json.RawMessage, result interface{}) error
+}
+
+// completedChild is a successful child execution that can be rolled back.
+type completedChild struct {
+	parentIdx  int
+	childIdx   int
+	parent     string
+	name       string
+	args       json.RawMessage
+	result     interface{}
+	rollbacker Rollbacker
+}
+
+// rollbackerOf returns the Rollbacker of a child, or nil if its effects cannot be undone.
+func rollbackerOf(child Child) Rollbacker {
+	if c, ok := child.(interface{ hasRollback() bool }); ok && !c.hasRollback() {
+		return nil
+	}
+	r, _ := child.(Rollbacker)
+	return r
+}
+
+// --- Policy State ---
+
+// stopped reports whether children that have not started yet must be skipped.
+func (e *execution) stopped() bool {
+	if e.policy == "" || e.policy == PolicyContinue {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.failure != ""
+}
+
+// fail records the failure of a child; only the first failure is kept.
+func (e *execution) fail(parent, child string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failure == "" {
+		e.failure = parent + "." + child
+	}
+}
+
+// observe records the outcome of a child execution: failures stop the request, and
+// successful children that can be rolled back are remembered under PolicyAllOrNothing.
+func (e *execution) observe(ctx context.Context, parent string, index int, req ToolKitChild, resp ChildResponse, child Child) {
+	if resp.Failed() {
+		e.fail(parent, resp.Name)
+		return
+	}
+	if e.policy != PolicyAllOrNothing || child == nil {
+		return
+	}
+	rollbacker := rollbackerOf(child)
+	if rollbacker == nil {
+		return
+	}
+	parentIdx, _ := ctx.Value(eventParentKey{}).(int)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.completed = append(e.completed, completedChild{
+		parentIdx:  parentIdx,
+		childIdx:   index,
+		parent:     parent,
+		name:       req.Name,
+		args:       req.Args,
+		result:     resp.Result,
+		rollbacker: rollbacker,
+	})
+}
+
+// skippedResponse returns the response of a child that was not started because of an earlier failure.
+func (e *execution) skippedResponse(name string) ChildResponse {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return NewChildError(name, NewError(CodeSkipped, fmt.Sprintf("Child '%s' was not executed because '%s' failed (policy %s)", name, e.failure, e.policy)))
+}
+
+// rollback undoes the completed children of a failed PolicyAllOrNothing request, in reverse
+// completion order, and replaces their responses with "rolled_back" or "rollback_failed" errors.
+// Rollbacks run even if the request context has expired, since the request already failed. A
+// panicking rollback is recovered and reported as "rollback_failed" with the panic details.
+func (e *execution) rollback(ctx context.Context, resp *ToolKitResponse) {
+	if e.policy != PolicyAllOrNothing {
+		return
+	}
+	e.mu.Lock()
+	failure, completed := e.failure, e.completed
+	e.mu.Unlock()
+	if failure == "" {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for i := len(completed) - 1; i >= 0; i-- {
+		c := completed[i]
+		result := NewError(CodeRolledBack, fmt.Sprintf("Child '%s' succeeded but was rolled back because '%s' failed", c.name, failure))
+		_, err := recoverPanic(ctx, c.name, func(ctx context.Context) (interface{}, error) {
+			return nil, c.rollbacker.Rollback(ctx, c.args, c.result)
+		})
+		if err != nil {
+			LoggerFromContext(ctx).Error("Child rollback failed", LogKeyParent, c.parent, LogKeyChild, c.name, LogKeyError, err.Error())
+			var opts []ErrorOption
+			var tkErr ToolKitError
+			if errors.As(err, &tkErr) && tkErr.Code == CodeHandlerPanic {
+				opts = append(opts, WithDetails(tkErr.Details()))
+			}
+			result = NewError(CodeRollbackFailed, fmt.Sprintf("Child '%s' succeeded, but rolling it back after '%s' failed returned: %v", c.name, failure, err), opts...)
+		}
+		if c.parentIdx < len(resp.Responses) && c.childIdx < len(resp.Responses[c.parentIdx].ChildsResponses) {
+			resp.Responses[c.parentIdx].ChildsResponses[c.childIdx] = NewChildError(c.name, result)
+		}
+	}
+}
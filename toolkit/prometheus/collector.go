@@ -0,0 +1,181 @@
+// Package prometheus exports toolkit metrics to Prometheus.
+// This file implements Collector, a toolkit.Metrics hook that is also a prometheus.Collector,
+// recording call counts, latencies, payload sizes and error codes of every child tool.
+package prometheus
+
+import (
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+)
+
+// Label names of the exported metrics.
+const (
+	LabelToolkit = "toolkit" // Toolkit name
+	LabelParent  = "parent"  // Parent name
+	LabelChild   = "child"   // Child name
+	LabelStatus  = "status"  // "ok" or "error"
+	LabelCode    = "code"    // ToolKitError code
+	LabelKind    = "kind"    // "parent", "child" or "tool" (flat tools) for unknown tool requests
+)
+
+// Collector records the observations of one or more toolkits as Prometheus metrics:
+//
+//   - <namespace>_child_invocations_total{toolkit,parent,child,status}
+//   - <namespace>_child_errors_total{toolkit,parent,child,code}
+//   - <namespace>_child_duration_seconds{toolkit,parent,child}
+//   - <namespace>_child_args_bytes{toolkit,parent,child}
+//   - <namespace>_child_result_bytes{toolkit,parent,child}
+//   - <namespace>_unknown_tools_total{toolkit,kind,parent}
+//
+// Requests for unknown parents, children and flat tools are only counted by unknown_tools_total.
+// Their names come from the model, so they are not used as labels: unknown parents and flat
+// tools have an empty parent label and unknown children are labeled with their (registered)
+// parent only.
+//
+// A Collector is safe for concurrent use. Register it with a prometheus.Registerer and pass
+// it to the toolkits with toolkit.WithMetrics.
+type Collector struct {
+	invocations *prom.CounterVec
+	errors      *prom.CounterVec
+	duration    *prom.HistogramVec
+	argsSize    *prom.HistogramVec
+	resultSize  *prom.HistogramVec
+	unknown     *prom.CounterVec
+}
+
+// collectorConfig holds the settings of a Collector under construction.
+type collectorConfig struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+}
+
+// CollectorOption configures a Collector created with NewCollector.
+type CollectorOption func(*collectorConfig)
+
+// WithNamespace sets the prefix of the metric names. The default is "toolkit".
+func WithNamespace(namespace string) CollectorOption {
+	return func(c *collectorConfig) {
+		c.namespace = namespace
+	}
+}
+
+// WithDurationBuckets sets the buckets, in seconds, of the duration histogram.
+// The default is prometheus.DefBuckets.
+func WithDurationBuckets(buckets ...float64) CollectorOption {
+	return func(c *collectorConfig) {
+		c.durationBuckets = buckets
+	}
+}
+
+// WithSizeBuckets sets the buckets, in bytes, of the argument and result size histograms.
+// The default is 64 bytes to 1 MiB in powers of four.
+func WithSizeBuckets(buckets ...float64) CollectorOption {
+	return func(c *collectorConfig) {
+		c.sizeBuckets = buckets
+	}
+}
+
+// NewCollector creates a Collector.
+//
+// Example:
+//
+//	metrics := prometheus.NewCollector()
+//	registry.MustRegister(metrics)
+//	tk := toolkit.NewWithOptions("my_toolkit", []toolkit.Option{toolkit.WithMetrics(metrics)}, parents...)
+func NewCollector(opts ...CollectorOption) *Collector {
+	cfg := collectorConfig{
+		namespace:       "toolkit",
+		durationBuckets: prom.DefBuckets,
+		sizeBuckets:     prom.ExponentialBuckets(64, 4, 8),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+
+	childLabels := []string{LabelToolkit, LabelParent, LabelChild}
+	return &Collector{
+		invocations: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "child_invocations_total",
+			Help:      "Number of child tool invocations.",
+		}, []string{LabelToolkit, LabelParent, LabelChild, LabelStatus}),
+		errors: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "child_errors_total",
+			Help:      "Number of failed child tool invocations by ToolKitError code.",
+		}, []string{LabelToolkit, LabelParent, LabelChild, LabelCode}),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "child_duration_seconds",
+			Help:      "Execution time of child tools in seconds.",
+			Buckets:   cfg.durationBuckets,
+		}, childLabels),
+		argsSize: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "child_args_bytes",
+			Help:      "Size of the arguments passed to child tools in bytes.",
+			Buckets:   cfg.sizeBuckets,
+		}, childLabels),
+		resultSize: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "child_result_bytes",
+			Help:      "Size of the JSON encoded results of successful child tools in bytes.",
+			Buckets:   cfg.sizeBuckets,
+		}, childLabels),
+		unknown: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "unknown_tools_total",
+			Help:      "Number of requests for parents, children or flat tools that do not exist.",
+		}, []string{LabelToolkit, LabelKind, LabelParent}),
+	}
+}
+
+// ObserveChild implements toolkit.Metrics.
+func (c *Collector) ObserveChild(obs toolkit.ChildObservation) {
+	switch obs.ErrorCode {
+	case toolkit.CodeParentNotFound:
+		c.unknown.WithLabelValues(obs.Toolkit, "parent", "").Inc()
+		return
+	case toolkit.CodeChildNotFound:
+		c.unknown.WithLabelValues(obs.Toolkit, "child", obs.Parent).Inc()
+		return
+	case toolkit.CodeToolNotFound:
+		c.unknown.WithLabelValues(obs.Toolkit, "tool", "").Inc()
+		return
+	}
+
+	status := "ok"
+	if obs.ErrorCode != "" {
+		status = "error"
+		c.errors.WithLabelValues(obs.Toolkit, obs.Parent, obs.Child, obs.ErrorCode).Inc()
+	}
+	c.invocations.WithLabelValues(obs.Toolkit, obs.Parent, obs.Child, status).Inc()
+	c.duration.WithLabelValues(obs.Toolkit, obs.Parent, obs.Child).Observe(obs.Duration.Seconds())
+	c.argsSize.WithLabelValues(obs.Toolkit, obs.Parent, obs.Child).Observe(float64(obs.ArgsSize))
+	if obs.ErrorCode == "" {
+		c.resultSize.WithLabelValues(obs.Toolkit, obs.Parent, obs.Child).Observe(float64(obs.ResultSize))
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+// collectors returns the metric vectors of the collector.
+func (c *Collector) collectors() []prom.Collector {
+	return []prom.Collector{c.invocations, c.errors, c.duration, c.argsSize, c.resultSize, c.unknown}
+}
@@ -0,0 +1,102 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the schema provider registry used by Toolkit.GetToolkitSchema, which lets
+// applications add tool definition formats for their own vendors or gateways.
+package toolkit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// SchemaProvider builds the tool definition of a Toolkit in the format of a specific
+// model vendor or gateway. Providers typically walk Toolkit.GetParents and each child's
+// GetInputSchema to transform the ToolKit request structure and child argument schemas.
+type SchemaProvider interface {
+	// Name returns the identifier passed to Toolkit.GetToolkitSchema (e.g., "openai").
+	Name() string
+	// Schema builds the provider-specific tool definition for the toolkit.
+	Schema(t *Toolkit) (interface{}, error)
+}
+
+// schemaProviderFunc adapts a function to the SchemaProvider interface.
+type schemaProviderFunc struct {
+	name string
+	fn   func(t *Toolkit) (interface{}, error)
+}
+
+func (p schemaProviderFunc) Name() string                           { return p.name }
+func (p schemaProviderFunc) Schema(t *Toolkit) (interface{}, error) { return p.fn(t) }
+
+// NewSchemaProvider creates a SchemaProvider from a name and a schema function.
+//
+// Example:
+//
+//	toolkit.RegisterSchemaProvider(toolkit.NewSchemaProvider("ollama", func(t *toolkit.Toolkit) (interface{}, error) {
+//	    tool, err := t.GenerateOpenAITool()
+//	    tool.Function.Strict = false
+//	    return tool, err
+//	}))
+func NewSchemaProvider(name string, fn func(t *Toolkit) (interface{}, error)) SchemaProvider {
+	return schemaProviderFunc{name: name, fn: fn}
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]SchemaProvider{}
+)
+
+func init() {
+	RegisterSchemaProvider(NewSchemaProvider("anthropic", func(t *Toolkit) (interface{}, error) {
+		return t.GenerateToolkitSchema(), nil
+	}))
+	RegisterSchemaProvider(NewSchemaProvider("openai", func(t *Toolkit) (interface{}, error) {
+		return t.GenerateOpenAITool()
+	}))
+	RegisterSchemaProvider(NewSchemaProvider("gemini", func(t *Toolkit) (interface{}, error) {
+		return t.GenerateGeminiFunctionDeclaration(), nil
+	}))
+}
+
+// RegisterSchemaProvider makes a SchemaProvider available to Toolkit.GetToolkitSchema
+// under its name. Registering a name again replaces the previous provider, which also
+// allows applications to customize the built-in "anthropic", "openai" and "gemini" providers.
+// It panics if the provider is nil or has an empty name, since that is a programming error.
+// RegisterSchemaProvider is safe for concurrent use.
+func RegisterSchemaProvider(p SchemaProvider) {
+	if p == nil {
+		panic("toolkit: RegisterSchemaProvider called with a nil provider")
+	}
+	name := p.Name()
+	if name == "" {
+		panic("toolkit: RegisterSchemaProvider called with an empty provider name")
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = p
+}
+
+// SchemaProviders returns the names of all registered schema providers, sorted.
+func SchemaProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupSchemaProvider returns the provider registered under name, if any.
+func lookupSchemaProvider(name string) (SchemaProvider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// unknownProviderError reports a provider name that is not registered.
+func unknownProviderError(name string) error {
+	return NewError(CodeUnknownSchemaProvider, fmt.Sprintf("Schema provider '%s' is not registered (available: %v)", name, SchemaProviders()))
+}
@@ -0,0 +1,186 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file implements the runtime registry of a Toolkit: adding and removing parents and
+// children while requests are served, and notifying listeners such as the MCP server of changes.
+package toolkit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind identifies the kind of a RegistryChange.
+type ChangeKind string
+
+const (
+	ChangeParentAdded   ChangeKind = "parent_added"   // A parent was added or replaced
+	ChangeParentRemoved ChangeKind = "parent_removed" // A parent was removed
+	ChangeChildAdded    ChangeKind = "child_added"    // A child was added or replaced
+	ChangeChildRemoved  ChangeKind = "child_removed"  // A child was removed
+)
+
+// RegistryChange describes a change of the parents or children of a toolkit.
+type RegistryChange struct {
+	Kind   ChangeKind // What changed
+	Parent string     // Name of the added or removed parent, or of the parent of the child
+	Child  string     // Name of the added or removed child; empty for parent changes
+}
+
+// --- Registry Changes ---
+
+// AddParent registers a parent while the toolkit may be serving requests, replacing any
+// parent with the same name. Requests that already started keep the parents they looked up.
+//
+// Returns:
+//   - error: An "invalid_arguments" ToolKitError if parent is nil
+func (t *Toolkit) AddParent(parent Parent) error {
+	if parent == nil {
+		return NewError(CodeInvalidArguments, fmt.Sprintf("Cannot add a nil parent to toolkit '%s'", t.name))
+	}
+	t.mu.Lock()
+	t.parents[parent.GetName()] = parent
+	t.mu.Unlock()
+
+	t.notify(RegistryChange{Kind: ChangeParentAdded, Parent: parent.GetName()})
+	return nil
+}
+
+// RemoveParent unregisters the named parent. Running children of the parent complete
+// normally; later requests for it get parent_not_found.
+//
+// Returns:
+//   - error: A "parent_not_found" ToolKitError if no parent has that name
+func (t *Toolkit) RemoveParent(name string) error {
+	t.mu.Lock()
+	_, ok := t.parents[name]
+	delete(t.parents, name)
+	t.mu.Unlock()
+
+	if !ok {
+		return t.parentNotFound(name)
+	}
+	t.notify(RegistryChange{Kind: ChangeParentRemoved, Parent: name})
+	return nil
+}
+
+// AddChild registers child with the named parent while the toolkit may be serving requests,
+// replacing any child with the same name.
+//
+// Returns:
+//   - error: A "parent_not_found" ToolKitError if no parent has that name, an
+//     "immutable_parent" ToolKitError if the parent is not a MutableParent, or an
+//     "invalid_arguments" ToolKitError if child is nil
+//
+// Example:
+//
+//	err := tk.AddChild("search", toolkit.NewChild("fetch_url", "Fetches a URL", fetchHandler))
+func (t *Toolkit) AddChild(parentName string, child Child) error {
+	if child == nil {
+		return NewError(CodeInvalidArguments, fmt.Sprintf("Cannot add a nil child to parent '%s'", parentName))
+	}
+	parent, err := t.mutableParent(parentName)
+	if err != nil {
+		return err
+	}
+	parent.AddChild(child)
+
+	t.notify(RegistryChange{Kind: ChangeChildAdded, Parent: parentName, Child: child.GetName()})
+	return nil
+}
+
+// RemoveChild unregisters the named child of the named parent. Running invocations of the
+// child complete normally; later requests for it get child_not_found.
+//
+// Returns:
+//   - error: A "parent_not_found" or "child_not_found" ToolKitError if the parent or the
+//     child does not exist, or an "immutable_parent" ToolKitError if the parent is not a
+//     MutableParent
+func (t *Toolkit) RemoveChild(parentName, childName string) error {
+	parent, err := t.mutableParent(parentName)
+	if err != nil {
+		return err
+	}
+	if !parent.RemoveChild(childName) {
+		return NewError(CodeChildNotFound, fmt.Sprintf("Child tool '%s' not found within parent '%s'", childName, parentName))
+	}
+
+	t.notify(RegistryChange{Kind: ChangeChildRemoved, Parent: parentName, Child: childName})
+	return nil
+}
+
+// OnChange registers fn to be called after every change made with AddParent, RemoveParent,
+// AddChild or RemoveChild. fn runs synchronously on the goroutine that made the change, so it
+// should return quickly. The returned function unregisters fn.
+//
+// Example:
+//
+//	stop := tk.OnChange(func(change toolkit.RegistryChange) {
+//	    log.Printf("%s: %s.%s", change.Kind, change.Parent, change.Child)
+//	})
+//	defer stop()
+func (t *Toolkit) OnChange(fn func(change RegistryChange)) (remove func()) {
+	if fn == nil {
+		return func() {}
+	}
+	t.listenersMu.Lock()
+	defer t.listenersMu.Unlock()
+	if t.listeners == nil {
+		t.listeners = map[int]func(RegistryChange){}
+	}
+	id := t.nextListener
+	t.nextListener++
+	t.listeners[id] = fn
+	return func() {
+		t.listenersMu.Lock()
+		defer t.listenersMu.Unlock()
+		delete(t.listeners, id)
+	}
+}
+
+// notify logs a registry change and passes it to the listeners registered with OnChange.
+func (t *Toolkit) notify(change RegistryChange) {
+	t.Logger().Info("Toolkit changed", "change", string(change.Kind), LogKeyParent, change.Parent, LogKeyChild, change.Child)
+
+	t.listenersMu.Lock()
+	listeners := make([]func(RegistryChange), 0, len(t.listeners))
+	for _, fn := range t.listeners {
+		listeners = append(listeners, fn)
+	}
+	t.listenersMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(change)
+	}
+}
+
+// --- Registry Lookups ---
+
+// parent returns the registered parent with the given name.
+func (t *Toolkit) parent(name string) (Parent, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	parent, ok := t.parents[name]
+	return parent, ok
+}
+
+// mutableParent returns the named parent if it supports AddChild and RemoveChild.
+func (t *Toolkit) mutableParent(name string) (MutableParent, error) {
+	parent, ok := t.parent(name)
+	if !ok {
+		return nil, t.parentNotFound(name)
+	}
+	mutable, ok := parent.(MutableParent)
+	if !ok {
+		return nil, NewError(CodeImmutableParent, fmt.Sprintf("Parent '%s' does not support adding or removing children", name))
+	}
+	return mutable, nil
+}
+
+// parentNotFound returns the parent_not_found error for name, listing the registered parents.
+func (t *Toolkit) parentNotFound(name string) error {
+	t.mu.RLock()
+	available := sortedNames(t.parents)
+	t.mu.RUnlock()
+	return NewError(CodeParentNotFound, fmt.Sprintf("Parent toolkit '%s' not registered", name),
+		WithDetails(map[string]any{"available": available}),
+		WithHint(fmt.Sprintf("Use one of the parents of the toolkit: %s.", strings.Join(available, ", "))))
+}
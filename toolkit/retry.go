@@ -0,0 +1,145 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file implements automatic retries with exponential backoff, so transient failures of
+// flaky tools are absorbed within a request instead of costing another model round-trip.
+package toolkit
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how often and how fast a failed child invocation is retried.
+// Zero fields take their defaults, so RetryPolicy{} retries retryable errors up to
+// three attempts in total.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. The default is 3.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the second attempt. The default is 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. The default is 10s.
+	MaxBackoff time.Duration
+
+	// Multiplier grows the delay after every attempt. The default is 2.
+	Multiplier float64
+
+	// Jitter is the fraction of every delay that is randomized, between 0 and 1, so that
+	// concurrent retries don't hit a recovering service at the same time. Zero means the
+	// default of 0.2; a negative value disables jitter.
+	Jitter float64
+
+	// RetryIf decides whether a failed attempt is retried. The default retries errors marked
+	// Retryable (see WithRetryable), such as timeouts. See RetryOnCodes.
+	RetryIf func(err ToolKitError) bool
+}
+
+// RetryOnCodes returns a RetryPolicy.RetryIf predicate that retries errors with one of the
+// given codes, or any error marked Retryable.
+//
+// Example:
+//
+//	toolkit.WithRetry(toolkit.RetryPolicy{
+//	    MaxAttempts: 4,
+//	    RetryIf:     toolkit.RetryOnCodes(toolkit.CodeHandlerExecutionError),
+//	})
+func RetryOnCodes(codes ...string) func(err ToolKitError) bool {
+	retryable := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		retryable[code] = true
+	}
+	return func(err ToolKitError) bool {
+		return err.Retryable || retryable[err.Code]
+	}
+}
+
+// withDefaults returns the policy with its zero fields replaced by the defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	if p.RetryIf == nil {
+		p.RetryIf = func(err ToolKitError) bool { return err.Retryable }
+	}
+	return p
+}
+
+// backoff returns the delay before the given attempt (2 for the first retry).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-2))
+	delay = min(delay, float64(p.MaxBackoff))
+	delay -= delay * p.Jitter * rand.Float64()
+	return time.Duration(delay)
+}
+
+// RetryMiddleware returns middleware that retries failed child invocations according to
+// the policy. Use it to add retries to custom Child implementations or to every child of a
+// parent or toolkit; children created with NewChild can use WithRetry instead.
+func RetryMiddleware(policy RetryPolicy) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call ChildCall) (interface{}, error) {
+			return retry(ctx, policy, call.Child, func(ctx context.Context) (interface{}, error) {
+				return next(ctx, call)
+			})
+		}
+	}
+}
+
+// retry runs fn until it succeeds, the policy gives up or ctx is done. The error of the
+// last attempt is returned. The number of attempts is recorded for the ChildResponse.
+func retry(ctx context.Context, policy RetryPolicy, child string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		recordAttempt(ctx, attempt)
+		result, err := fn(ctx)
+		if err == nil {
+			return result, nil
+		}
+
+		tkErr := *NewChildError(child, err).Error
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.RetryIf(tkErr) {
+			return nil, err
+		}
+		delay := policy.backoff(attempt + 1)
+		LoggerFromContext(ctx).Warn("Retrying child", append([]interface{}{LogKeyAttempt, attempt, LogKeyBackoff, delay}, errorAttrs(tkErr)...)...)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// attemptsKey is the context key under which handleChild collects the attempt count of a child.
+type attemptsKey struct{}
+
+// withAttempts returns a copy of ctx in which retries record their attempt count into n.
+func withAttempts(ctx context.Context, n *atomic.Int32) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, n)
+}
+
+// recordAttempt records the current attempt of the child executing under ctx.
+func recordAttempt(ctx context.Context, attempt int) {
+	if n, ok := ctx.Value(attemptsKey{}).(*atomic.Int32); ok {
+		n.Store(int32(attempt))
+	}
+}
@@ -0,0 +1,212 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file builds the request schema of a Toolkit from its registered parents and children,
+// so that models receive the real argument shapes of every child tool.
+package toolkit
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/invopop/jsonschema"
+)
+
+// GenerateToolkitSchema builds the JSON schema of a ToolKit request for this toolkit instance.
+// Unlike GetToolKitSchemaForAnthropic, which reflects the generic ToolKit type and leaves
+// `args` opaque, the generated schema is specific to the registered tools:
+//   - `name` is restricted to the toolkit name
+//   - `parents[].name` is an enum of the registered parent names
+//   - `parents[].childs[].name` is an enum of the children of the selected parent
+//   - `parents[].childs[].args` is tied to the child's GetInputSchema() through `oneOf`
+//     variants discriminated by `const` parent and child names
+//   - every argument also accepts a `{"$ref": "..."}` reference to another child's result
+//
+// Parents and children are emitted in name order so the schema is stable across calls.
+func (t *Toolkit) GenerateToolkitSchema() *jsonschema.Schema {
+	parents := t.sortedParents()
+
+	parentNames := make([]any, 0, len(parents))
+	parentVariants := make([]*jsonschema.Schema, 0, len(parents))
+	for _, parent := range parents {
+		parentNames = append(parentNames, parent.GetName())
+		parentVariants = append(parentVariants, t.parentVariantSchema(parent))
+	}
+
+	parentItem := objectSchema(
+		prop("name", &jsonschema.Schema{Type: "string", Description: "The name of the parent toolkit to execute."}),
+		prop("childs", &jsonschema.Schema{
+			Type:        "array",
+			Description: "The child tools to execute within this parent.",
+			Items: objectSchema(
+				prop("name", &jsonschema.Schema{Type: "string", Description: "The name of the child tool to execute."}),
+				prop("args", &jsonschema.Schema{Type: "object", Description: "The arguments for the child tool, as a JSON object."}),
+			),
+		}),
+	)
+	if len(parents) > 0 {
+		parentItem.Properties.Value("name").Enum = parentNames
+		parentItem.OneOf = parentVariants
+	}
+
+	root := objectSchema(
+		prop("name", &jsonschema.Schema{Type: "string", Description: "The name of the toolkit.", Enum: []any{t.name}}),
+		prop("parents", &jsonschema.Schema{
+			Type:        "array",
+			Description: "The parent toolkits to execute within the toolkit.",
+			Items:       parentItem,
+		}),
+	)
+	root.Version = jsonschema.Version
+	return root
+}
+
+// parentVariantSchema returns the `oneOf` variant that applies when `parents[].name`
+// equals the name of the given parent. It restricts the child names to the parent's
+// children and ties each child's `args` to that child's input schema.
+func (t *Toolkit) parentVariantSchema(parent Parent) *jsonschema.Schema {
+	children := sortedChildren(parent)
+
+	childsSchema := &jsonschema.Schema{Type: "array"}
+	if len(children) == 0 {
+		// A parent without children cannot execute anything
+		childsSchema.MaxItems = &[]uint64{0}[0]
+	} else {
+		childNames := make([]any, 0, len(children))
+		childVariants := make([]*jsonschema.Schema, 0, len(children))
+		for _, child := range children {
+			childNames = append(childNames, child.GetName())
+			childVariants = append(childVariants, objectSchema(
+				prop("name", &jsonschema.Schema{Const: child.GetName()}),
+				prop("args", refArgsSchema(t.childArgsSchema(parent.GetName(), child))),
+			))
+		}
+		childItem := objectSchema(prop("name", &jsonschema.Schema{Type: "string", Enum: childNames}))
+		childItem.OneOf = childVariants
+		childsSchema.Items = childItem
+	}
+
+	return objectSchema(
+		prop("name", &jsonschema.Schema{Const: parent.GetName()}),
+		prop("childs", childsSchema),
+	)
+}
+
+// childArgsSchema converts the input schema of a child into an embeddable sub-schema.
+// Children without a usable input schema accept any object.
+func (t *Toolkit) childArgsSchema(parentName string, child Child) *jsonschema.Schema {
+	if s := t.embeddableSchema(parentName, child.GetName(), child.GetInputSchema()); s != nil {
+		return s
+	}
+	return &jsonschema.Schema{Type: "object"}
+}
+
+// childOutputSchema converts the output schema of a child (see TypedChild) into an
+// embeddable sub-schema, or returns nil if the child does not declare one.
+func (t *Toolkit) childOutputSchema(parentName string, child Child) *jsonschema.Schema {
+	return t.embeddableSchema(parentName, child.GetName(), OutputSchemaOf(child))
+}
+
+// embeddableSchema converts a child schema into an embeddable sub-schema, or returns nil
+// if there is none or it cannot be converted. Schemas produced by GenerateSchema are copied
+// without their `$schema`/`$id` headers; other schema values are converted through their
+// JSON representation.
+func (t *Toolkit) embeddableSchema(parentName, childName string, schema interface{}) *jsonschema.Schema {
+	switch s := schema.(type) {
+	case *jsonschema.Schema:
+		if s == nil {
+			return nil
+		}
+		embedded := *s
+		embedded.Version = ""
+		embedded.ID = ""
+		return &embedded
+	case nil:
+		return nil
+	default:
+		var embedded jsonschema.Schema
+		if !t.convertChildSchema(parentName, childName, s, &embedded) {
+			return nil
+		}
+		embedded.Version = ""
+		embedded.ID = ""
+		return &embedded
+	}
+}
+
+// convertChildSchema converts a child schema into out through its JSON representation.
+// Failures are logged with the parent and child names and reported as false.
+func (t *Toolkit) convertChildSchema(parentName, childName string, schema, out interface{}) bool {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		t.Logger().Error("Error marshaling child schema", LogKeyParent, parentName, LogKeyChild, childName, LogKeyError, err.Error())
+		return false
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Logger().Error("Error converting child schema", LogKeyParent, parentName, LogKeyChild, childName, LogKeyError, err.Error())
+		return false
+	}
+	return true
+}
+
+// schemaProperty is a named property used to build object schemas with objectSchema.
+type schemaProperty struct {
+	name   string
+	schema *jsonschema.Schema
+}
+
+// prop is a shorthand constructor for schemaProperty.
+func prop(name string, schema *jsonschema.Schema) schemaProperty {
+	return schemaProperty{name: name, schema: schema}
+}
+
+// objectSchema builds an object schema with the given properties, in order.
+// Every listed property is marked as required.
+func objectSchema(props ...schemaProperty) *jsonschema.Schema {
+	s := &jsonschema.Schema{
+		Type:       "object",
+		Properties: jsonschema.NewProperties(),
+	}
+	for _, p := range props {
+		s.Properties.Set(p.name, p.schema)
+		s.Required = append(s.Required, p.name)
+	}
+	return s
+}
+
+// sortedParents returns the registered parents ordered by name.
+func (t *Toolkit) sortedParents() []Parent {
+	t.mu.RLock()
+	parents := make([]Parent, 0, len(t.parents))
+	for _, p := range t.parents {
+		parents = append(parents, p)
+	}
+	t.mu.RUnlock()
+	sort.Slice(parents, func(i, j int) bool {
+		return parents[i].GetName() < parents[j].GetName()
+	})
+	return parents
+}
+
+// sortedNames returns the keys of a registry ordered by name.
+func sortedNames[T any](registry map[string]T) []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedChildren returns the children of a parent ordered by name.
+func sortedChildren(parent Parent) []Child {
+	childMap := parent.GetChildren()
+	children := make([]Child, 0, len(childMap))
+	for _, c := range childMap {
+		if c != nil {
+			children = append(children, c)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].GetName() < children[j].GetName()
+	})
+	return children
+}
@@ -0,0 +1,417 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the Google Gemini / Vertex AI schema provider, which emits a FunctionDeclaration
+// in the OpenAPI 3 schema subset Gemini accepts, and the converter for Gemini function calls.
+package toolkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GeminiFunctionDeclaration is the function declaration expected by the Gemini API and
+// Vertex AI in the `functionDeclarations` of a tool. It marshals to the same JSON as
+// genai.FunctionDeclaration, so it can be converted with a JSON round trip.
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// geminiFormats lists the `format` values Gemini accepts, per schema type.
+var geminiFormats = map[string][]string{
+	"STRING":  {"date-time", "enum"},
+	"INTEGER": {"int32", "int64"},
+	"NUMBER":  {"float", "double"},
+}
+
+// geminiKeywords lists the schema keywords copied unchanged from child schemas.
+// Everything else (`$schema`, `additionalProperties`, `$defs`, `exclusiveMinimum`, ...)
+// is dropped; the toolkit still enforces it at execution time through argument validation.
+var geminiKeywords = []string{
+	"title", "description", "pattern", "minLength", "maxLength", "minItems", "maxItems",
+	"minimum", "maximum", "minProperties", "maxProperties", "default", "example",
+}
+
+// GenerateGeminiFunctionDeclaration builds a Gemini function declaration for this toolkit.
+// Gemini only accepts an OpenAPI 3 subset with limited `anyOf` support, so the parent and
+// child variants of GenerateToolkitSchema cannot be expressed. Instead the parameters are
+// keyed by name, which keeps every child's arguments typed without any combinator:
+//
+//	{"<parent>": {"<child>": [<args>, <args>, ...]}}
+//
+// Each entry of a child's list invokes that child once. Gemini rejects objects without
+// properties, so children without arguments are a boolean instead, set to true to invoke
+// them once. Argument properties Gemini cannot express (maps, arrays without item schema,
+// values of any type) are left out; children that require such a property are omitted
+// and logged. Parents without children are omitted.
+//
+// Use ParseGeminiFunctionCall to turn the resulting function call into HandleToolKit input.
+func (t *Toolkit) GenerateGeminiFunctionDeclaration() GeminiFunctionDeclaration {
+	parentProps := map[string]interface{}{}
+	for _, parent := range t.sortedParents() {
+		children := sortedChildren(parent)
+		if len(children) == 0 {
+			continue
+		}
+		childProps := make(map[string]interface{}, len(children))
+		for _, child := range children {
+			if schema, ok := t.geminiChildSchema(parent.GetName(), child); ok {
+				childProps[child.GetName()] = schema
+			}
+		}
+		if len(childProps) == 0 {
+			continue
+		}
+		parentProps[parent.GetName()] = map[string]interface{}{
+			"type":        "OBJECT",
+			"description": parent.GetDescription(),
+			"properties":  childProps,
+		}
+	}
+
+	declaration := GeminiFunctionDeclaration{
+		Name: t.name,
+		Description: fmt.Sprintf("Executes tools of the %s toolkit. Each property is a parent, and each nested "+
+			"property is a child tool of that parent. A child runs once per arguments object in its list.", t.name),
+	}
+	if len(parentProps) > 0 {
+		declaration.Parameters = map[string]interface{}{
+			"type":       "OBJECT",
+			"properties": parentProps,
+		}
+	}
+	return declaration
+}
+
+// ParseGeminiFunctionCall converts the name and args of a Gemini `functionCall` part into
+// input for HandleToolKit. The args follow the layout of GenerateGeminiFunctionDeclaration;
+// they may be given as a json.RawMessage, []byte, string, or the decoded map of the SDK.
+// Raw JSON keeps the parent and child order chosen by the model; decoded maps are
+// processed in name order. Null-valued properties, which Gemini sends for omitted
+// nullable fields, are removed.
+//
+// Example:
+//
+//	input, err := myToolkit.ParseGeminiFunctionCall(call.Name, call.Args)
+//	if err != nil { ... }
+//	resp, err := myToolkit.HandleToolKit(ctx, input)
+func (t *Toolkit) ParseGeminiFunctionCall(name string, args interface{}) (json.RawMessage, error) {
+	if name != t.name {
+		return nil, fmt.Errorf("function call '%s' does not belong to toolkit '%s'", name, t.name)
+	}
+
+	var raw []byte
+	switch a := args.(type) {
+	case json.RawMessage:
+		raw = a
+	case []byte:
+		raw = a
+	case string:
+		raw = []byte(a)
+	default:
+		var err error
+		if raw, err = json.Marshal(a); err != nil {
+			return nil, fmt.Errorf("error marshaling Gemini function call args: %w", err)
+		}
+	}
+
+	request := ToolKit{Name: t.name, ToolKitParents: []ToolKitParent{}}
+	parents, err := orderedFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling Gemini function call args: %w", err)
+	}
+	for _, parent := range parents {
+		children, err := orderedFields(parent.value)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling children of parent '%s': %w", parent.key, err)
+		}
+		parentReq := ToolKitParent{Name: parent.key, ToolKitChilds: []ToolKitChild{}}
+		for _, child := range children {
+			calls, err := geminiChildCalls(child.value)
+			if err != nil {
+				return nil, fmt.Errorf("error unmarshaling args of child '%s.%s': %w", parent.key, child.key, err)
+			}
+			for _, callArgs := range calls {
+				parentReq.ToolKitChilds = append(parentReq.ToolKitChilds, ToolKitChild{Name: child.key, Args: callArgs})
+			}
+		}
+		request.ToolKitParents = append(request.ToolKitParents, parentReq)
+	}
+	return json.Marshal(request)
+}
+
+// orderedField is a property of a JSON object with its raw value.
+type orderedField struct {
+	key   string
+	value json.RawMessage
+}
+
+// orderedFields decodes a JSON object into its properties, in document order.
+// Null and empty input yield no properties; null-valued properties are skipped.
+func orderedFields(raw json.RawMessage) ([]orderedField, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	var fields []orderedField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if bytes.Equal(value, []byte("null")) {
+			continue
+		}
+		fields = append(fields, orderedField{key: tok.(string), value: value})
+	}
+	return fields, nil
+}
+
+// geminiChildCalls returns the arguments of every invocation of a child. A single
+// object is accepted as one invocation, since models occasionally drop the list, and
+// the boolean of a child without arguments invokes it once when true.
+func geminiChildCalls(raw json.RawMessage) ([]json.RawMessage, error) {
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if invoke, ok := value.(bool); ok {
+		if !invoke {
+			return nil, nil
+		}
+		return []json.RawMessage{json.RawMessage(`{}`)}, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+	calls := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("expected an arguments object, got %s", jsonTypeOf(item))
+		}
+		callArgs, err := json.Marshal(dropNullProperties(item))
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, callArgs)
+	}
+	return calls, nil
+}
+
+// geminiChildSchema returns the property of a child in the function declaration: a list
+// of argument objects, or a boolean if the child takes no argument Gemini can express.
+// It returns false if a required argument cannot be expressed in the Gemini schema subset.
+func (t *Toolkit) geminiChildSchema(parentName string, child Child) (map[string]interface{}, bool) {
+	noArgs := map[string]interface{}{
+		"type":        "BOOLEAN",
+		"description": child.GetDescription() + " Takes no arguments; set to true to run it.",
+	}
+	var schema map[string]interface{}
+	if !t.convertChildSchema(parentName, child.GetName(), t.childArgsSchema(parentName, child), &schema) {
+		return noArgs, true
+	}
+
+	if args, ok := toGeminiSchema(schema); ok && args["type"] == "OBJECT" {
+		return map[string]interface{}{
+			"type":        "ARRAY",
+			"description": child.GetDescription(),
+			"items":       args,
+		}, true
+	}
+	if required, _ := schema["required"].([]interface{}); len(required) > 0 {
+		t.Logger().Warn("Child omitted from the Gemini schema: a required argument cannot be expressed",
+			LogKeyParent, parentName, LogKeyChild, child.GetName())
+		return nil, false
+	}
+	return noArgs, true
+}
+
+// toGeminiSchema rewrites a decoded JSON schema into the Gemini schema subset:
+// upper-case types, `nullable` instead of null types, string-only enums, and
+// `anyOf` only where no simpler form exists. It returns false if the schema cannot
+// be expressed: Gemini rejects objects without properties, arrays without an item
+// schema and schemas without a type. Such optional properties are left out.
+func toGeminiSchema(node interface{}) (map[string]interface{}, bool) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		// Boolean schemas carry no type information
+		return nil, false
+	}
+
+	out := map[string]interface{}{}
+	nullable := schema["nullable"] == true
+
+	// Combinators: a single non-null variant collapses into a nullable schema
+	var variants []interface{}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		if list, ok := schema[keyword].([]interface{}); ok {
+			variants = append(variants, list...)
+		}
+	}
+	var nonNull []interface{}
+	for _, variant := range variants {
+		if v, ok := variant.(map[string]interface{}); ok && v["type"] == "null" {
+			nullable = true
+			continue
+		}
+		nonNull = append(nonNull, variant)
+	}
+	var converted []interface{}
+	for _, variant := range nonNull {
+		if variant, ok := toGeminiSchema(variant); ok {
+			converted = append(converted, variant)
+		}
+	}
+	if len(nonNull) > 0 && len(converted) == 0 {
+		return nil, false
+	}
+	if len(converted) == 1 {
+		out = converted[0].(map[string]interface{})
+	} else if len(converted) > 1 {
+		out["anyOf"] = converted
+	}
+
+	// Types: a null member becomes `nullable`, several members become `anyOf`
+	var types []string
+	switch typ := schema["type"].(type) {
+	case string:
+		types = []string{typ}
+	case []interface{}:
+		for _, member := range typ {
+			if s, ok := member.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	var nonNullTypes []string
+	for _, typ := range types {
+		if typ == "null" {
+			nullable = true
+			continue
+		}
+		nonNullTypes = append(nonNullTypes, strings.ToUpper(typ))
+	}
+	if len(nonNullTypes) == 1 {
+		out["type"] = nonNullTypes[0]
+	} else if len(nonNullTypes) > 1 {
+		members := make([]interface{}, 0, len(nonNullTypes))
+		for _, typ := range nonNullTypes {
+			members = append(members, map[string]interface{}{"type": typ})
+		}
+		out["anyOf"] = members
+	}
+
+	for _, keyword := range geminiKeywords {
+		if value, ok := schema[keyword]; ok {
+			out[keyword] = value
+		}
+	}
+
+	// Enums: Gemini only accepts string enums; other values are described instead
+	enum, hasEnum := schema["enum"].([]interface{})
+	if c, ok := schema["const"]; ok {
+		enum, hasEnum = []interface{}{c}, true
+	}
+	if hasEnum {
+		values := make([]interface{}, 0, len(enum))
+		allStrings := true
+		for _, value := range enum {
+			if value == nil {
+				nullable = true
+				continue
+			}
+			if _, ok := value.(string); !ok {
+				allStrings = false
+			}
+			values = append(values, value)
+		}
+		if allStrings {
+			out["type"] = "STRING"
+			out["format"] = "enum"
+			out["enum"] = values
+		} else {
+			note := "Allowed values: " + formatEnum(values) + "."
+			if description, ok := out["description"].(string); ok && description != "" {
+				note = description + " " + note
+			}
+			out["description"] = note
+		}
+	}
+
+	if format, ok := schema["format"].(string); ok {
+		if typ, ok := out["type"].(string); ok && slices.Contains(geminiFormats[typ], format) {
+			out["format"] = format
+		}
+	}
+
+	if props, ok := schema["properties"].(map[string]interface{}); ok && len(props) > 0 {
+		required := map[string]bool{}
+		if list, ok := schema["required"].([]interface{}); ok {
+			for _, name := range list {
+				if s, ok := name.(string); ok {
+					required[s] = true
+				}
+			}
+		}
+
+		converted := make(map[string]interface{}, len(props))
+		var requiredNames []string
+		for name, prop := range props {
+			prop, ok := toGeminiSchema(prop)
+			if !ok {
+				if required[name] {
+					return nil, false
+				}
+				continue
+			}
+			converted[name] = prop
+			if required[name] {
+				requiredNames = append(requiredNames, name)
+			}
+		}
+		out["type"] = "OBJECT"
+		if len(converted) > 0 {
+			out["properties"] = converted
+		}
+		if len(requiredNames) > 0 {
+			sort.Strings(requiredNames)
+			out["required"] = requiredNames
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		if items, ok := toGeminiSchema(items); ok {
+			out["items"] = items
+		}
+	}
+
+	switch {
+	case out["type"] == nil && out["anyOf"] == nil:
+		return nil, false
+	case out["type"] == "OBJECT" && out["properties"] == nil:
+		return nil, false
+	case out["type"] == "ARRAY" && out["items"] == nil:
+		return nil, false
+	}
+	if nullable {
+		out["nullable"] = true
+	}
+	return out, true
+}
@@ -0,0 +1,311 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the OpenAI function-calling schema provider, which emits a tool definition
+// compatible with OpenAI structured outputs (strict mode), and the matching argument helper.
+package toolkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OpenAITool is the tool definition expected by the OpenAI Chat Completions API
+// (and compatible endpoints) in the `tools` array of a request.
+type OpenAITool struct {
+	Type     string         `json:"type"` // Always "function"
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes the function of an OpenAITool.
+type OpenAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	Strict      bool                   `json:"strict"`
+}
+
+// openAIUnsupportedKeywords lists the JSON schema keywords that OpenAI strict mode rejects.
+// They are removed from child schemas; the toolkit still enforces them at execution time
+// through argument validation.
+var openAIUnsupportedKeywords = []string{
+	"$schema", "$id", "$anchor", "$comment", "allOf", "not", "if", "then", "else",
+	"dependentSchemas", "dependentRequired", "patternProperties", "propertyNames",
+	"unevaluatedProperties", "minLength", "maxLength", "minProperties", "maxProperties",
+	"uniqueItems", "contains", "minContains", "maxContains", "prefixItems", "default",
+	"examples", "deprecated", "readOnly", "writeOnly", "contentEncoding", "contentMediaType",
+}
+
+// GenerateOpenAITool builds a strict-mode OpenAI tool definition for this toolkit.
+// Strict mode only accepts a subset of JSON schema, so the parameters are built as follows:
+//   - every object sets `additionalProperties: false` and lists all of its properties as required
+//   - properties that were optional become nullable (the model sends `null` to omit them)
+//   - parent and child variants use `anyOf` instead of `oneOf`, each variant fully specified
+//   - unsupported keywords (see openAIUnsupportedKeywords) are removed
+//   - every child argument also accepts a `{"$ref": "..."}` reference to another child's result
+//
+// Strict mode cannot express objects with arbitrary keys, such as map-typed arguments, so
+// children whose input schema declares `additionalProperties` cannot be offered to OpenAI.
+//
+// Use ParseOpenAIArguments to turn the resulting tool-call arguments into HandleToolKit input.
+//
+// Returns:
+//   - OpenAITool: The tool definition
+//   - error: An "unsupported_schema" ToolKitError naming the child and the offending schema
+//     location if a child's input schema cannot be expressed in strict mode
+func (t *Toolkit) GenerateOpenAITool() (OpenAITool, error) {
+	parents := t.sortedParents()
+
+	parentVariants := make([]interface{}, 0, len(parents))
+	for _, parent := range parents {
+		children := sortedChildren(parent)
+		childVariants := make([]interface{}, 0, len(children))
+		for _, child := range children {
+			args, err := t.openAIArgsSchema(parent.GetName(), child)
+			if err != nil {
+				return OpenAITool{}, err
+			}
+			childVariants = append(childVariants, strictObject(map[string]interface{}{
+				"name": map[string]interface{}{"type": "string", "enum": []interface{}{child.GetName()}},
+				"args": args,
+			}, child.GetDescription()))
+		}
+
+		childsSchema := map[string]interface{}{"type": "array"}
+		if len(childVariants) == 0 {
+			childsSchema["maxItems"] = 0
+			childsSchema["items"] = strictObject(map[string]interface{}{}, "")
+		} else {
+			childsSchema["items"] = map[string]interface{}{"anyOf": childVariants}
+		}
+		parentVariants = append(parentVariants, strictObject(map[string]interface{}{
+			"name":   map[string]interface{}{"type": "string", "enum": []interface{}{parent.GetName()}},
+			"childs": childsSchema,
+		}, parent.GetDescription()))
+	}
+
+	parentsSchema := map[string]interface{}{
+		"type":        "array",
+		"description": "The parent toolkits to execute within the toolkit.",
+	}
+	if len(parentVariants) == 0 {
+		parentsSchema["maxItems"] = 0
+		parentsSchema["items"] = strictObject(map[string]interface{}{}, "")
+	} else {
+		parentsSchema["items"] = map[string]interface{}{"anyOf": parentVariants}
+	}
+
+	return OpenAITool{
+		Type: "function",
+		Function: OpenAIFunction{
+			Name:        t.name,
+			Description: t.GetToolkitDescription(),
+			Parameters: strictObject(map[string]interface{}{
+				"name":    map[string]interface{}{"type": "string", "enum": []interface{}{t.name}, "description": "The name of the toolkit."},
+				"parents": parentsSchema,
+			}, ""),
+			Strict: true,
+		},
+	}, nil
+}
+
+// ParseOpenAIArguments converts the `arguments` string of an OpenAI tool call into
+// input for HandleToolKit. Strict mode makes the model send `null` for optional
+// properties it wants to omit, so every null-valued object property is removed
+// before the arguments reach the child schemas and handlers.
+//
+// Example:
+//
+//	input, err := toolkit.ParseOpenAIArguments(toolCall.Function.Arguments)
+//	if err != nil { ... }
+//	resp, err := myToolkit.HandleToolKit(ctx, input)
+func ParseOpenAIArguments(arguments string) (json.RawMessage, error) {
+	var value interface{}
+	dec := json.NewDecoder(strings.NewReader(arguments))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("error unmarshaling OpenAI tool call arguments: %w", err)
+	}
+	return json.Marshal(dropNullProperties(value))
+}
+
+// dropNullProperties recursively removes null-valued properties from objects.
+func dropNullProperties(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if child == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = dropNullProperties(child)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropNullProperties(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// openAIArgsSchema converts a child's input schema into a strict-mode compatible schema.
+func (t *Toolkit) openAIArgsSchema(parentName string, child Child) (interface{}, error) {
+	var schema interface{}
+	if !t.convertChildSchema(parentName, child.GetName(), t.childArgsSchema(parentName, child), &schema) {
+		return strictObject(map[string]interface{}{}, ""), nil
+	}
+	if _, ok := schema.(map[string]interface{}); !ok {
+		// Boolean schemas (true) accept anything; strict mode needs an explicit object
+		return strictObject(map[string]interface{}{}, ""), nil
+	}
+	strict, err := toStrictSchema(schema, "")
+	if err != nil {
+		return nil, NewError(CodeUnsupportedSchema, fmt.Sprintf(
+			"Input schema of child '%s' of parent '%s' is not supported by OpenAI strict mode: %v", child.GetName(), parentName, err),
+			WithHint("Replace map-typed arguments with a struct or a list of key/value objects."))
+	}
+	return withStrictRefs(strict), nil
+}
+
+// withStrictRefs lets every property of a strict args schema also accept a reference object.
+func withStrictRefs(node interface{}) interface{} {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+	props, _ := schema["properties"].(map[string]interface{})
+	for name, prop := range props {
+		props[name] = map[string]interface{}{"anyOf": []interface{}{prop, strictObject(map[string]interface{}{
+			refKey: map[string]interface{}{"type": "string", "description": "The result of another child of this request: <parent>.<child>#<n>/<json pointer>."},
+		}, "")}}
+	}
+	return schema
+}
+
+// toStrictSchema rewrites a decoded JSON schema into the OpenAI strict-mode subset.
+// pointer is the location of node within the child schema, used in errors.
+func toStrictSchema(node interface{}, pointer string) (interface{}, error) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node, nil
+	}
+	if extra, ok := schema["additionalProperties"]; ok && extra != false {
+		// Strict mode forces additionalProperties to false, which would leave the model unable
+		// to send any of the keys the child accepts
+		return nil, fmt.Errorf("%s/additionalProperties: objects with arbitrary keys cannot be expressed", pointer)
+	}
+
+	for _, keyword := range openAIUnsupportedKeywords {
+		delete(schema, keyword)
+	}
+	if oneOf, ok := schema["oneOf"]; ok {
+		delete(schema, "oneOf")
+		schema["anyOf"] = oneOf
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for i, sub := range anyOf {
+			strict, err := toStrictSchema(sub, fmt.Sprintf("%s/anyOf/%d", pointer, i))
+			if err != nil {
+				return nil, err
+			}
+			anyOf[i] = strict
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		strict, err := toStrictSchema(items, pointer+"/items")
+		if err != nil {
+			return nil, err
+		}
+		schema["items"] = strict
+	}
+	if defs, ok := schema["$defs"].(map[string]interface{}); ok {
+		for _, name := range sortedPropertyNames(defs) {
+			strict, err := toStrictSchema(defs[name.(string)], pointer+"/$defs/"+name.(string))
+			if err != nil {
+				return nil, err
+			}
+			defs[name.(string)] = strict
+		}
+	}
+
+	props, hasProps := schema["properties"].(map[string]interface{})
+	if !hasProps && schema["type"] != "object" {
+		return schema, nil
+	}
+	if !hasProps {
+		props = map[string]interface{}{}
+		schema["properties"] = props
+	}
+
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	for _, key := range sortedPropertyNames(props) {
+		name := key.(string)
+		prop, err := toStrictSchema(props[name], pointer+"/properties/"+name)
+		if err != nil {
+			return nil, err
+		}
+		if !required[name] {
+			prop = nullable(prop)
+		}
+		props[name] = prop
+	}
+	schema["required"] = sortedPropertyNames(props)
+	schema["additionalProperties"] = false
+	return schema, nil
+}
+
+// nullable allows null in addition to the values accepted by schema.
+func nullable(node interface{}) interface{} {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []interface{}{typ, "null"}
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			schema["enum"] = append(enum, nil)
+		}
+		return schema
+	}
+	return map[string]interface{}{
+		"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}},
+	}
+}
+
+// strictObject builds a strict-mode object schema where every property is required.
+func strictObject(props map[string]interface{}, description string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             sortedPropertyNames(props),
+		"additionalProperties": false,
+	}
+	if description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+// sortedPropertyNames returns the property names of a schema in lexical order.
+func sortedPropertyNames(props map[string]interface{}) []interface{} {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		list = append(list, name)
+	}
+	return list
+}
@@ -0,0 +1,287 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	sdk "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+	tkanthropic "github.com/h-ess/ai-toolkit/toolkit/anthropic"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Test Helpers ---
+
+// fakeMessagesAPI serves scripted Messages API responses and records the requests.
+type fakeMessagesAPI struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses []string
+	requests  []map[string]interface{}
+}
+
+func (f *fakeMessagesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	assert.Equal(f.t, "/v1/messages", r.URL.Path)
+
+	var req map[string]interface{}
+	assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+	f.requests = append(f.requests, req)
+
+	if len(f.responses) == 0 {
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"no scripted response"}}`, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(f.responses[0]))
+	f.responses = f.responses[1:]
+}
+
+func startFakeMessagesAPI(t *testing.T, responses ...string) (*sdk.Client, *fakeMessagesAPI) {
+	t.Helper()
+	fake := &fakeMessagesAPI{t: t, responses: responses}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+	client := sdk.NewClient(option.WithBaseURL(ts.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+	return client, fake
+}
+
+// fakeMessage builds a Messages API response with the given content blocks.
+func fakeMessage(stopReason string, content ...string) string {
+	blocks := "[" + joinJSON(content) + "]"
+	return `{"id":"msg","type":"message","role":"assistant","model":"claude-3-7-sonnet-20250219",` +
+		`"content":` + blocks + `,"stop_reason":"` + stopReason + `","stop_sequence":null,` +
+		`"usage":{"input_tokens":10,"output_tokens":5}}`
+}
+
+func joinJSON(items []string) string {
+	out := ""
+	for i, item := range items {
+		if i > 0 {
+			out += ","
+		}
+		out += item
+	}
+	return out
+}
+
+func toolUseBlock(id, name, input string) string {
+	return `{"type":"tool_use","id":"` + id + `","name":"` + name + `","input":` + input + `}`
+}
+
+func textBlock(text string) string {
+	return `{"type":"text","text":"` + text + `"}`
+}
+
+func createRunnerTestToolkit(t *testing.T) *toolkit.Toolkit {
+	t.Helper()
+	return toolkit.New("runner_tk",
+		createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true)),
+	)
+}
+
+func runnerParams() sdk.MessageNewParams {
+	return sdk.MessageNewParams{
+		Model:     sdk.F(sdk.ModelClaude3_7Sonnet20250219),
+		MaxTokens: sdk.Int(256),
+	}
+}
+
+func toolkitInput(child, val string) string {
+	return `{"name":"runner_tk","parents":[{"name":"p1","childs":[{"name":"` + child + `","args":{"val":"` + val + `"}}]}]}`
+}
+
+// --- Test Runner ---
+
+func TestRunner_BatchesToolResultsIntoOneUserTurn(t *testing.T) {
+	client, fake := startFakeMessagesAPI(t,
+		fakeMessage("tool_use",
+			textBlock("Let me check."),
+			toolUseBlock("tu_1", "runner_tk", toolkitInput("c1a", "x")),
+			toolUseBlock("tu_2", "runner_tk", toolkitInput("c1err", "y")),
+		),
+		fakeMessage("end_turn", textBlock("All done.")),
+	)
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams())
+
+	transcript, err := runner.Run(context.Background(), "Do things")
+	require.NoError(t, err)
+	assert.Equal(t, tkanthropic.StopReasonCompleted, transcript.StopReason)
+	assert.Equal(t, "All done.", transcript.FinalText())
+	assert.Equal(t, tkanthropic.Usage{InputTokens: 20, OutputTokens: 10}, transcript.Usage)
+
+	require.Len(t, transcript.Turns, 2)
+	calls := transcript.Turns[0].ToolCalls
+	require.Len(t, calls, 2)
+	assert.Equal(t, "tu_1", calls[0].ID)
+	assert.False(t, calls[0].IsError)
+	assert.Contains(t, calls[0].Content, "r1a:x")
+	assert.Equal(t, "tu_2", calls[1].ID)
+	assert.True(t, calls[1].IsError)
+	assert.Contains(t, calls[1].Content, "handler_execution_error")
+	assert.Empty(t, transcript.Turns[1].ToolCalls)
+
+	// History: prompt, assistant, one user message with both results, assistant
+	assert.Len(t, transcript.Messages, 4)
+
+	require.Len(t, fake.requests, 2)
+	first := fake.requests[0]
+	tools := first["tools"].([]interface{})
+	require.Len(t, tools, 1)
+	assert.Equal(t, "runner_tk", tools[0].(map[string]interface{})["name"])
+
+	messages := fake.requests[1]["messages"].([]interface{})
+	require.Len(t, messages, 3)
+	last := messages[2].(map[string]interface{})
+	assert.Equal(t, "user", last["role"])
+	content := last["content"].([]interface{})
+	require.Len(t, content, 2, "Both tool results must be sent in a single user message")
+	for i, id := range []string{"tu_1", "tu_2"} {
+		block := content[i].(map[string]interface{})
+		assert.Equal(t, "tool_result", block["type"])
+		assert.Equal(t, id, block["tool_use_id"])
+	}
+}
+
+func TestRunner_PartialFailureIsFlagged(t *testing.T) {
+	input := `{"name":"runner_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"x"}},{"name":"c1err","args":{}}]}]}`
+	client, fake := startFakeMessagesAPI(t,
+		fakeMessage("tool_use", toolUseBlock("tu_1", "runner_tk", input)),
+		fakeMessage("end_turn", textBlock("ok")),
+	)
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams())
+
+	transcript, err := runner.Run(context.Background(), "Mixed")
+	require.NoError(t, err)
+	calls := transcript.Turns[0].ToolCalls
+	require.Len(t, calls, 1)
+	assert.True(t, calls[0].IsError, "A batch with a failed child should be flagged like a failed flat tool")
+	assert.Contains(t, calls[0].Content, "r1a:x", "Successful results are still returned")
+
+	messages := fake.requests[1]["messages"].([]interface{})
+	block := messages[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, block["is_error"])
+}
+
+func TestRunner_MaxTurns(t *testing.T) {
+	toolTurn := fakeMessage("tool_use", toolUseBlock("tu", "runner_tk", toolkitInput("c1a", "x")))
+	client, fake := startFakeMessagesAPI(t, toolTurn, toolTurn, toolTurn)
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams(), tkanthropic.WithMaxTurns(2))
+
+	transcript, err := runner.Run(context.Background(), "Loop")
+	require.NoError(t, err)
+	assert.Equal(t, tkanthropic.StopReasonMaxTurns, transcript.StopReason)
+	assert.Len(t, transcript.Turns, 2)
+	assert.Len(t, fake.requests, 2)
+}
+
+func TestRunner_MaxTurnsZeroUsesDefault(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		client, fake := startFakeMessagesAPI(t, fakeMessage("end_turn", textBlock("All done.")))
+		runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams(), tkanthropic.WithMaxTurns(n))
+
+		transcript, err := runner.Run(context.Background(), "Hello")
+		require.NoError(t, err)
+		assert.NotEqual(t, tkanthropic.StopReasonMaxTurns, transcript.StopReason, "WithMaxTurns(%d) should fall back to the default", n)
+		assert.Equal(t, "All done.", transcript.FinalText())
+		assert.Len(t, fake.requests, 1)
+	}
+}
+
+func TestRunner_StopConditionAndHooks(t *testing.T) {
+	client, fake := startFakeMessagesAPI(t,
+		fakeMessage("tool_use", toolUseBlock("tu", "runner_tk", toolkitInput("c1a", "x"))),
+		fakeMessage("end_turn", textBlock("unused")),
+	)
+
+	var requests, responses, toolCalls int
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams(),
+		tkanthropic.WithStopCondition(func(turn tkanthropic.Turn) bool {
+			return len(turn.ToolCalls) > 0 && !turn.ToolCalls[0].IsError
+		}),
+		tkanthropic.WithHooks(tkanthropic.Hooks{
+			BeforeRequest: func(ctx context.Context, turn int, params *sdk.MessageNewParams) error {
+				requests++
+				params.Temperature = sdk.F(0.1)
+				return nil
+			},
+			AfterResponse: func(ctx context.Context, turn int, response *sdk.Message) error {
+				responses++
+				return nil
+			},
+			AfterToolCall: func(ctx context.Context, turn int, call tkanthropic.ToolCall) error {
+				toolCalls++
+				assert.Equal(t, "tu", call.ID)
+				return nil
+			},
+		}),
+	)
+
+	transcript, err := runner.Run(context.Background(), "Once")
+	require.NoError(t, err)
+	assert.Equal(t, tkanthropic.StopReasonStopCondition, transcript.StopReason)
+	assert.Equal(t, []int{1, 1, 1}, []int{requests, responses, toolCalls})
+	require.Len(t, fake.requests, 1)
+	assert.Equal(t, 0.1, fake.requests[0]["temperature"], "BeforeRequest should be able to adjust params")
+}
+
+func TestRunner_HookErrorAborts(t *testing.T) {
+	client, _ := startFakeMessagesAPI(t, fakeMessage("end_turn", textBlock("hi")))
+	hookErr := errors.New("budget exceeded")
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams(),
+		tkanthropic.WithHooks(tkanthropic.Hooks{
+			AfterResponse: func(context.Context, int, *sdk.Message) error { return hookErr },
+		}),
+	)
+
+	transcript, err := runner.Run(context.Background(), "Hi")
+	assert.ErrorIs(t, err, hookErr)
+	assert.Len(t, transcript.Messages, 2, "The partial transcript should be returned")
+}
+
+func TestRunner_FlatTools(t *testing.T) {
+	client, fake := startFakeMessagesAPI(t,
+		fakeMessage("tool_use",
+			toolUseBlock("tu_1", "p1__c1a", `{"val":"flat"}`),
+			toolUseBlock("tu_2", "p1__missing", `{}`),
+		),
+		fakeMessage("end_turn", textBlock("ok")),
+	)
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams(), tkanthropic.WithFlatTools())
+
+	transcript, err := runner.Run(context.Background(), "Flat")
+	require.NoError(t, err)
+
+	tools := fake.requests[0]["tools"].([]interface{})
+	names := []string{}
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	assert.Equal(t, []string{"p1__c1a", "p1__c1err"}, names)
+
+	calls := transcript.Turns[0].ToolCalls
+	require.Len(t, calls, 2)
+	assert.False(t, calls[0].IsError)
+	assert.Contains(t, calls[0].Content, "r1a:flat")
+	assert.True(t, calls[1].IsError)
+	assert.Contains(t, calls[1].Content, "tool_not_found")
+}
+
+func TestRunner_APIError(t *testing.T) {
+	client, _ := startFakeMessagesAPI(t)
+	runner := tkanthropic.NewRunner(client, createRunnerTestToolkit(t), runnerParams())
+
+	transcript, err := runner.Run(context.Background(), "Hi")
+	require.Error(t, err)
+	assert.Empty(t, transcript.Turns)
+}
@@ -5,13 +5,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	// Import the package we are testing
 	// We use the exported functions like toolkit.NewChild
 	"github.com/h-ess/ai-toolkit/toolkit"
 
+	"github.com/invopop/jsonschema"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // --- Test Structs ---
@@ -128,9 +133,348 @@ func TestNewChild_Handle_UnmarshalError(t *testing.T) {
 	t.Logf("Got expected error: %v", err) // Log for confirmation
 }
 
+type ValidatedArgs struct {
+	Name  string   `json:"name" jsonschema:"required"`
+	Mode  string   `json:"mode,omitempty" jsonschema:"enum=fast,enum=slow"`
+	Count int      `json:"count,omitempty" jsonschema:"minimum=1"`
+	Tags  []string `json:"tags,omitempty" jsonschema:"maxItems=2"`
+}
+
+func TestNewChild_Handle_SchemaViolations(t *testing.T) {
+	handler := func(ctx context.Context, args ValidatedArgs) (interface{}, error) {
+		t.Fatal("Handler called unexpectedly with invalid arguments")
+		return nil, nil
+	}
+	child := toolkit.NewChild("validated_child", "desc", handler)
+
+	inputArgsJSON := json.RawMessage(`{"mode":"medium","count":0,"tags":["a","b",3],"extra":true}`)
+	_, err := child.Handle(context.Background(), inputArgsJSON)
+
+	tkErr, ok := err.(toolkit.ToolKitError)
+	if !ok {
+		t.Fatalf("Expected error type toolkit.ToolKitError, got %T", err)
+	}
+	if tkErr.Code != "invalid_arguments" {
+		t.Errorf("Expected error code 'invalid_arguments', got '%s'", tkErr.Code)
+	}
+
+	pointers := make([]string, 0, len(tkErr.Violations()))
+	for _, v := range tkErr.Violations() {
+		pointers = append(pointers, v.Pointer)
+	}
+	assert.ElementsMatch(t, []string{"/name", "/count", "/extra", "/mode", "/tags", "/tags/2"}, pointers)
+	assert.Contains(t, tkErr.Message, "/name: required property is missing")
+	assert.Contains(t, tkErr.Message, "/extra: unknown property is not allowed")
+}
+
+type PatternArgs struct {
+	Name string `json:"name" jsonschema:"pattern=^[a-z]+$"`
+}
+
+type InvalidPatternArgs struct {
+	Name string `json:"name" jsonschema:"pattern=^(?!tmp)"`
+}
+
+func TestNewChild_Handle_Patterns(t *testing.T) {
+	child := toolkit.NewChild("pattern_child", "desc", func(ctx context.Context, args PatternArgs) (interface{}, error) {
+		return args.Name, nil
+	})
+	result, err := child.Handle(context.Background(), json.RawMessage(`{"name":"abc"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", result)
+
+	_, err = child.Handle(context.Background(), json.RawMessage(`{"name":"ABC"}`))
+	assert.Equal(t, "invalid_arguments", errorCode(t, err))
+
+	schema := &jsonschema.Schema{Type: "object", PatternProperties: map[string]*jsonschema.Schema{
+		"^x_": {Type: "string", Pattern: "^[0-9]+$"},
+	}}
+	violations := toolkit.ValidateArgs(schema, json.RawMessage(`{"x_a":"12","x_b":"nope","other":"nope"}`))
+	require.Len(t, violations, 1)
+	assert.Equal(t, "/x_b", violations[0].Pointer)
+}
+
+func TestNewChild_Handle_InvalidPattern(t *testing.T) {
+	child := toolkit.NewChild("pattern_child", "desc", func(ctx context.Context, args InvalidPatternArgs) (interface{}, error) {
+		t.Fatal("Handler called unexpectedly with an invalid schema")
+		return nil, nil
+	})
+	_, err := child.Handle(context.Background(), json.RawMessage(`{"name":"x"}`))
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, "invalid_schema", tkErr.Code)
+	assert.Contains(t, tkErr.Message, `"^(?!tmp)"`)
+
+	violations := toolkit.ValidateArgs(child.GetInputSchema().(*jsonschema.Schema), json.RawMessage(`{"name":"x"}`))
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Message, "not a valid regular expression")
+}
+
+func TestNewChild_Handle_ValidArgsPassValidation(t *testing.T) {
+	handler := func(ctx context.Context, args ValidatedArgs) (interface{}, error) {
+		return SimpleResponse{Output: args.Name + ":" + args.Mode}, nil
+	}
+	child := toolkit.NewChild("validated_child", "desc", handler)
+
+	result, err := child.Handle(context.Background(), json.RawMessage(`{"name":"n","mode":"slow","count":2,"tags":["a"]}`))
+	if err != nil {
+		t.Fatalf("Handle failed unexpectedly: %v", err)
+	}
+	assert.Equal(t, SimpleResponse{Output: "n:slow"}, result)
+}
+
+type OptionalArgs struct {
+	Name  string   `json:"name" jsonschema:"required"`
+	Opt   *string  `json:"opt,omitempty"`
+	Limit *int     `json:"limit,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+func TestNewChild_Handle_NullOptionalFields(t *testing.T) {
+	handler := func(ctx context.Context, args OptionalArgs) (interface{}, error) {
+		return SimpleResponse{Output: fmt.Sprintf("%s:%v:%v:%d", args.Name, args.Opt == nil, args.Limit == nil, len(args.Tags))}, nil
+	}
+	child := toolkit.NewChild("optional_child", "desc", handler)
+
+	result, err := child.Handle(context.Background(), json.RawMessage(`{"name":"x","opt":null,"limit":null,"tags":null}`))
+	require.NoError(t, err, "null should be accepted for optional fields")
+	assert.Equal(t, SimpleResponse{Output: "x:true:true:0"}, result)
+
+	_, err = child.Handle(context.Background(), json.RawMessage(`{"name":null}`))
+	tkErr, ok := err.(toolkit.ToolKitError)
+	require.True(t, ok, "Expected a ToolKitError, got %v", err)
+	assert.Equal(t, "invalid_arguments", tkErr.Code)
+	assert.Contains(t, tkErr.Message, "/name: expected string, got null", "Required fields still reject null")
+}
+
+func TestNewChild_Handle_NullArgsReportRequired(t *testing.T) {
+	handler := func(ctx context.Context, args SimpleArgs) (interface{}, error) {
+		t.Fatal("Handler called unexpectedly with missing arguments")
+		return nil, nil
+	}
+	child := toolkit.NewChild("null_args_child", "desc", handler)
+
+	_, err := child.Handle(context.Background(), json.RawMessage(`null`))
+	tkErr, ok := err.(toolkit.ToolKitError)
+	if !ok {
+		t.Fatalf("Expected error type toolkit.ToolKitError, got %T", err)
+	}
+	if len(tkErr.Violations()) != 1 || tkErr.Violations()[0].Pointer != "/input" {
+		t.Errorf("Expected a single violation for /input, got %+v", tkErr.Violations())
+	}
+}
+
+// createBlockingChild returns a child whose handler ignores its context and sleeps
+// for the given delay, simulating a slow, non-cooperative tool.
+func createBlockingChild(t *testing.T, name string, delay time.Duration, opts ...toolkit.ChildOption) toolkit.Child {
+	t.Helper()
+	handler := func(ctx context.Context, args SimpleArgs) (interface{}, error) {
+		time.Sleep(delay)
+		return SimpleResponse{Output: name + ":" + args.Input}, nil
+	}
+	return toolkit.NewChild[SimpleArgs](name, "desc_"+name, handler, opts...)
+}
+
+func TestNewChild_Handle_Timeout(t *testing.T) {
+	child := createBlockingChild(t, "slow_child", time.Second, toolkit.WithChildTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	_, err := child.Handle(context.Background(), json.RawMessage(`{"input":"in"}`))
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected a timeout error from Handle, got nil")
+	}
+	tkErr, ok := err.(toolkit.ToolKitError)
+	if !ok {
+		t.Fatalf("Expected error type toolkit.ToolKitError, got %T", err)
+	}
+	if tkErr.Code != "timeout" {
+		t.Errorf("Expected error code 'timeout', got '%s'", tkErr.Code)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("Handle should return once the timeout expires, took %v", elapsed)
+	}
+}
+
+func TestNewChild_Handle_Canceled(t *testing.T) {
+	child := createBlockingChild(t, "canceled_child", time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := child.Handle(ctx, json.RawMessage(`{"input":"in"}`))
+
+	tkErr, ok := err.(toolkit.ToolKitError)
+	if !ok {
+		t.Fatalf("Expected error type toolkit.ToolKitError, got %T", err)
+	}
+	if tkErr.Code != "canceled" {
+		t.Errorf("Expected error code 'canceled', got '%s'", tkErr.Code)
+	}
+}
+
+func TestNewChild_Handle_RequestTimeoutOverride(t *testing.T) {
+	child := createBlockingChild(t, "overridden_child", 30*time.Millisecond, toolkit.WithChildTimeout(5*time.Millisecond))
+
+	ctx := toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{ChildTimeout: time.Second})
+	result, err := child.Handle(ctx, json.RawMessage(`{"input":"in"}`))
+
+	if err != nil {
+		t.Fatalf("Expected the request override to extend the child timeout, got: %v", err)
+	}
+	assert.Equal(t, SimpleResponse{Output: "overridden_child:in"}, result)
+}
+
 // --- TestNewParent ---
 
 // Helper function to create a simple child for parent tests
+// --- TestToolKitError ---
+
+func TestToolKitError_WrapsHandlerErrors(t *testing.T) {
+	child := toolkit.NewChild("reader", "desc_reader", func(ctx context.Context, args SimpleArgs) (interface{}, error) {
+		switch args.Input {
+		case "custom":
+			return nil, fmt.Errorf("reader: %w", toolkit.NewError("quota_exceeded", "Out of quota", toolkit.WithRetryable(true)))
+		case "slow":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("reader: %w", os.ErrNotExist)
+	}, toolkit.WithChildTimeout(10*time.Millisecond))
+
+	// Plain errors are wrapped into handler_execution_error without losing the original
+	_, err := child.Handle(context.Background(), json.RawMessage(`{"input":"a.txt"}`))
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, toolkit.CodeHandlerExecutionError, tkErr.Code)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.ErrorIs(t, err, toolkit.ToolKitError{Code: toolkit.CodeHandlerExecutionError}, "ToolKitErrors match by code")
+	assert.NotErrorIs(t, err, toolkit.ToolKitError{Code: toolkit.CodeTimeout})
+	assert.False(t, tkErr.Retryable)
+
+	// Wrapped ToolKitErrors are recognized and kept
+	_, err = child.Handle(context.Background(), json.RawMessage(`{"input":"custom"}`))
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, "quota_exceeded", tkErr.Code)
+	assert.True(t, tkErr.Retryable)
+
+	// Timeouts are retryable and keep the context error
+	_, err = child.Handle(context.Background(), json.RawMessage(`{"input":"slow"}`))
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, toolkit.CodeTimeout, tkErr.Code)
+	assert.True(t, tkErr.Retryable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestToolKitError_Serialization(t *testing.T) {
+	err := toolkit.NewError("rate_limited", "Search API rate limited",
+		toolkit.WithCause(errors.New("HTTP 429")),
+		toolkit.WithDetails(map[string]any{"retry_after_seconds": 30}),
+		toolkit.WithRetryable(true),
+		toolkit.WithHint("Wait before searching again."),
+	)
+	assert.Equal(t, "rate_limited: Search API rate limited", err.Error())
+	assert.EqualError(t, errors.Unwrap(err), "HTTP 429")
+
+	raw, marshalErr := json.Marshal(err)
+	require.NoError(t, marshalErr)
+	assert.JSONEq(t, `{"Code":"rate_limited","Message":"Search API rate limited","Details":{"retry_after_seconds":30},"Retryable":true,"Hint":"Wait before searching again."}`, string(raw),
+		"The cause is not serialized")
+
+	raw, marshalErr = json.Marshal(toolkit.NewError(toolkit.CodeSkipped, "skipped"))
+	require.NoError(t, marshalErr)
+	assert.JSONEq(t, `{"Code":"skipped","Message":"skipped"}`, string(raw), "Unset fields are omitted")
+}
+
+func TestToolKitError_Comparable(t *testing.T) {
+	assert.True(t, toolkit.NewError("x", "y") == toolkit.NewError("x", "y"))
+	assert.False(t, toolkit.NewError("x", "y") == toolkit.NewError("x", "z"))
+
+	withDetails := toolkit.NewError("x", "y", toolkit.WithDetails(map[string]any{"k": 1}))
+	assert.NotPanics(t, func() {
+		var target error = toolkit.NewError("x", "y", toolkit.WithDetails(map[string]any{"k": 1}))
+		_ = withDetails == target
+		_ = errors.Is(withDetails, target)
+	})
+	assert.True(t, withDetails == withDetails, "A copy of an error equals the original")
+}
+
+func TestToolKitError_ViolationsRoundTrip(t *testing.T) {
+	child := toolkit.NewChild("validated_child", "desc", func(ctx context.Context, args ValidatedArgs) (interface{}, error) {
+		return nil, nil
+	})
+	_, err := child.Handle(context.Background(), json.RawMessage(`{"name":"n","count":0}`))
+	raw, marshalErr := json.Marshal(err)
+	require.NoError(t, marshalErr)
+	assert.Contains(t, string(raw), `"Violations":[{"Pointer":"/count","Message":`, "Violations use the casing of the error")
+
+	var decoded toolkit.ToolKitError
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, err.(toolkit.ToolKitError).Violations(), decoded.Violations())
+	assert.Equal(t, toolkit.CodeInvalidArguments, decoded.Code)
+}
+
+func TestToolKitError_NotFoundHints(t *testing.T) {
+	parent := toolkit.NewParent("files", "desc_files", createTestChild(t, "write", "w", false), createTestChild(t, "read", "r", false))
+	tk := toolkit.New("hint_tk", parent)
+	input := `{"name":"hint_tk","parents":[{"name":"files","childs":[{"name":"delete","args":{}}]},{"name":"network","childs":[{"name":"get","args":{}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	childErr := resp.Responses[0].ChildsResponses[0].Error
+	require.NotNil(t, childErr)
+	assert.Equal(t, toolkit.CodeChildNotFound, childErr.Code)
+	assert.Equal(t, []string{"read", "write"}, childErr.Details()["available"])
+	assert.Equal(t, "Use one of the children of 'files': read, write.", childErr.Hint)
+
+	parentErr := resp.Responses[1].ChildsResponses[0].Error
+	require.NotNil(t, parentErr)
+	assert.Equal(t, toolkit.CodeParentNotFound, parentErr.Code)
+	assert.Equal(t, "Use one of the parents of the toolkit: files.", parentErr.Hint)
+}
+
+// --- TestNewTypedChild ---
+
+func TestNewTypedChild_OutputSchema(t *testing.T) {
+	child := toolkit.NewTypedChild("typed", "desc_typed", func(ctx context.Context, args SimpleArgs) (SimpleResponse, error) {
+		if args.Input == "fail" {
+			return SimpleResponse{}, errors.New("boom")
+		}
+		return SimpleResponse{Output: "typed:" + args.Input}, nil
+	})
+
+	typed, ok := child.(toolkit.TypedChild)
+	require.True(t, ok, "NewTypedChild should implement TypedChild")
+	raw, err := json.Marshal(typed.GetOutputSchema())
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"properties":{"output":{"type":"string"}}`)
+	assert.Equal(t, typed.GetOutputSchema(), toolkit.OutputSchemaOf(child))
+	assert.Nil(t, toolkit.OutputSchemaOf(createTestChild(t, "untyped", "", false)), "NewChild declares no output schema")
+	assert.NotNil(t, toolkit.OutputSchemaOf(toolkit.WrapChild(child)), "WrapChild should keep the output schema")
+
+	// Handling is unchanged: results are returned as is, errors are wrapped
+	result, err := child.Handle(context.Background(), json.RawMessage(`{"input":"x"}`))
+	require.NoError(t, err)
+	assert.Equal(t, SimpleResponse{Output: "typed:x"}, result)
+	_, err = child.Handle(context.Background(), json.RawMessage(`{"input":"fail"}`))
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, "handler_execution_error", tkErr.Code)
+
+	// The output schema is published in the description and the flat tools
+	tk := toolkit.New("typed_tk", toolkit.NewParent("p", "desc_p", child, createTestChild(t, "untyped", "", false)))
+	assert.Contains(t, tk.GetToolkitDescription(), `</input_schema><output_schema>{`)
+	assert.Equal(t, 1, strings.Count(tk.GetToolkitDescription(), "<output_schema>"))
+	flat := tk.GetFlatTools()
+	require.Len(t, flat, 2)
+	require.NotNil(t, flat[0].OutputSchema)
+	assert.Empty(t, flat[0].OutputSchema.Version, "Embedded schemas have no $schema header")
+	assert.Nil(t, flat[1].OutputSchema)
+}
+
 func createTestChild(t *testing.T, name string, output string, shouldError bool) toolkit.Child {
 	t.Helper()
 	handler := func(ctx context.Context, args SimpleArgs) (interface{}, error) {
@@ -204,9 +548,9 @@ func TestNewParent_HandleChildren_Success(t *testing.T) {
 	if resp1.Name != "child1" {
 		t.Errorf("Expected child name 'child1', got '%s'", resp1.Name)
 	}
-	result1, ok := resp1.Response.(SimpleResponse)
+	result1, ok := resp1.Result.(SimpleResponse)
 	if !ok {
-		t.Fatalf("Expected response type SimpleResponse for child1, got %T", resp1.Response)
+		t.Fatalf("Expected response type SimpleResponse for child1, got %T", resp1.Result)
 	}
 	if result1.Output != "out1:in1" {
 		t.Errorf("Expected output 'out1:in1', got '%s'", result1.Output)
@@ -217,9 +561,9 @@ func TestNewParent_HandleChildren_Success(t *testing.T) {
 	if resp2.Name != "child2" {
 		t.Errorf("Expected child name 'child2', got '%s'", resp2.Name)
 	}
-	result2, ok := resp2.Response.(SimpleResponse)
+	result2, ok := resp2.Result.(SimpleResponse)
 	if !ok {
-		t.Fatalf("Expected response type SimpleResponse for child2, got %T", resp2.Response)
+		t.Fatalf("Expected response type SimpleResponse for child2, got %T", resp2.Result)
 	}
 	if result2.Output != "out2:in2" {
 		t.Errorf("Expected output 'out2:in2', got '%s'", result2.Output)
@@ -247,9 +591,9 @@ func TestNewParent_HandleChildren_ChildNotFound(t *testing.T) {
 	if resp2.Name != "non_existent_child" {
 		t.Errorf("Expected child name 'non_existent_child', got '%s'", resp2.Name)
 	}
-	tkErr, ok := resp2.Response.(toolkit.ToolKitError)
+	tkErr, ok := resp2.Err().(toolkit.ToolKitError)
 	if !ok {
-		t.Fatalf("Expected response type toolkit.ToolKitError for non_existent_child, got %T", resp2.Response)
+		t.Fatalf("Expected response type toolkit.ToolKitError for non_existent_child, got %#v", resp2)
 	}
 	if tkErr.Code != "child_not_found" {
 		t.Errorf("Expected error code 'child_not_found', got '%s'", tkErr.Code)
@@ -279,12 +623,46 @@ func TestNewParent_HandleChildren_ChildError(t *testing.T) {
 	if resp2.Name != "childWithError" {
 		t.Errorf("Expected child name 'childWithError', got '%s'", resp2.Name)
 	}
-	tkErr, ok := resp2.Response.(toolkit.ToolKitError)
+	tkErr, ok := resp2.Err().(toolkit.ToolKitError)
 	if !ok {
-		t.Fatalf("Expected response type toolkit.ToolKitError for childWithError, got %T", resp2.Response)
+		t.Fatalf("Expected response type toolkit.ToolKitError for childWithError, got %#v", resp2)
 	}
 	if tkErr.Code != "handler_execution_error" {
 		t.Errorf("Expected error code 'handler_execution_error', got '%s'", tkErr.Code)
 	}
 	t.Logf("Got expected error from child handler: %v", tkErr)
 }
+
+func TestNewParent_HandleChildren_ParentTimeout(t *testing.T) {
+	fastChild := createBlockingChild(t, "fast", time.Millisecond)
+	slowChild := createBlockingChild(t, "slow", time.Second)
+	parent := toolkit.NewParentWithOptions("test_parent_timeout", "desc",
+		[]toolkit.ParentOption{toolkit.WithParentTimeout(30 * time.Millisecond)},
+		fastChild, slowChild,
+	)
+
+	requests := []toolkit.ToolKitChild{
+		{Name: "fast", Args: json.RawMessage(`{"input":"in1"}`)},
+		{Name: "slow", Args: json.RawMessage(`{"input":"in2"}`)},
+		{Name: "fast", Args: json.RawMessage(`{"input":"in3"}`)},
+	}
+
+	parentResp := parent.HandleChildren(context.Background(), requests)
+	if len(parentResp.ChildsResponses) != 3 {
+		t.Fatalf("Expected 3 child responses, got %d", len(parentResp.ChildsResponses))
+	}
+
+	// The first child finished before the deadline and keeps its result
+	assert.Equal(t, SimpleResponse{Output: "fast:in1"}, parentResp.ChildsResponses[0].Result)
+
+	// The slow child and the one queued behind it both hit the parent deadline
+	for _, resp := range parentResp.ChildsResponses[1:] {
+		tkErr, ok := resp.Err().(toolkit.ToolKitError)
+		if !ok {
+			t.Fatalf("Expected response type toolkit.ToolKitError for %s, got %T", resp.Name, resp.Result)
+		}
+		if tkErr.Code != "timeout" {
+			t.Errorf("Expected error code 'timeout' for %s, got '%s'", resp.Name, tkErr.Code)
+		}
+	}
+}
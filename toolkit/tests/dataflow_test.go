@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Data Flow Test Helpers ---
+
+type fileArgs struct {
+	Path    string `json:"path"`
+	Content string `json:"content,omitempty"`
+}
+
+type fileResult struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// createFileParent returns a parent with read_file and write_file children backed by an in-memory store.
+func createFileParent(t *testing.T, files map[string]string, mu *sync.Mutex) toolkit.Parent {
+	t.Helper()
+	read := toolkit.NewChild("read_file", "Reads a file", func(ctx context.Context, args fileArgs) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		content, ok := files[args.Path]
+		if !ok {
+			return nil, toolkit.NewError("file_not_found", args.Path)
+		}
+		return fileResult{Path: args.Path, Content: content}, nil
+	})
+	write := toolkit.NewChild("write_file", "Writes a file", func(ctx context.Context, args fileArgs) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		files[args.Path] = args.Content
+		return fileResult{Path: args.Path, Content: args.Content}, nil
+	})
+	return createTestParent(t, "operations", read, write)
+}
+
+// --- Test Data Flow ---
+
+func TestHandleToolKit_RefReadThenWrite(t *testing.T) {
+	var mu sync.Mutex
+	files := map[string]string{"a.txt": "hello"}
+	tk := toolkit.New("flow_tk", createFileParent(t, files, &mu))
+
+	// The write is listed first: references decide the execution order, not the request order
+	input := `{"name":"flow_tk","parents":[{"name":"operations","childs":[
+		{"name":"write_file","args":{"path":"b.txt","content":{"$ref":"operations.read_file#0/content"}}},
+		{"name":"read_file","args":{"path":"a.txt"}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	require.Len(t, resp.Responses, 1)
+	require.Len(t, resp.Responses[0].ChildsResponses, 2)
+	assert.Equal(t, "write_file", resp.Responses[0].ChildsResponses[0].Name, "Responses should keep the request order")
+	assert.Equal(t, fileResult{Path: "b.txt", Content: "hello"}, resp.Responses[0].ChildsResponses[0].Result)
+	assert.Equal(t, fileResult{Path: "a.txt", Content: "hello"}, resp.Responses[0].ChildsResponses[1].Result)
+	assert.Equal(t, "hello", files["b.txt"])
+}
+
+func TestHandleToolKit_RefOccurrenceAndWholeResult(t *testing.T) {
+	var mu sync.Mutex
+	files := map[string]string{"a.txt": "first", "b.txt": "second"}
+	echo := toolkit.NewChild("echo", "Echoes its input", func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return args, nil
+	})
+	tk := toolkit.New("flow_tk", createFileParent(t, files, &mu), createTestParent(t, "util", echo))
+
+	input := `{"name":"flow_tk","parents":[
+		{"name":"operations","childs":[
+			{"name":"read_file","args":{"path":"a.txt"}},
+			{"name":"read_file","args":{"path":"b.txt"}}
+		]},
+		{"name":"util","childs":[
+			{"name":"echo","args":{"second":{"$ref":"operations.read_file#1/content"},"all":[{"$ref":"operations.read_file"}]}}
+		]}
+	]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	echoed, ok := resp.Responses[1].ChildsResponses[0].Result.(map[string]interface{})
+	require.True(t, ok, "Unexpected response %#v", resp.Responses[1].ChildsResponses[0])
+	assert.Equal(t, "second", echoed["second"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"path": "a.txt", "content": "first"}}, echoed["all"],
+		"A reference without fragment should resolve to the whole result of the first occurrence")
+}
+
+func TestHandleToolKit_RefUnresolved(t *testing.T) {
+	var mu sync.Mutex
+	tk := toolkit.New("flow_tk", createFileParent(t, map[string]string{"a.txt": "hello"}, &mu))
+
+	input := `{"name":"flow_tk","parents":[{"name":"operations","childs":[
+		{"name":"read_file","args":{"path":"a.txt"}},
+		{"name":"write_file","args":{"path":"b.txt","content":{"$ref":"operations.read_file#0/missing"}}},
+		{"name":"write_file","args":{"path":"c.txt","content":{"$ref":"operations.read_file#3/content"}}},
+		{"name":"write_file","args":{"path":"d.txt","content":{"$ref":"nope.read_file#0"}}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	childs := resp.Responses[0].ChildsResponses
+	require.Len(t, childs, 4)
+	assert.Equal(t, fileResult{Path: "a.txt", Content: "hello"}, childs[0].Result)
+	for i, want := range []string{"property 'missing' not found", "occurrence 3", "does not match any child"} {
+		resp := childs[i+1]
+		assert.Equal(t, "unresolved_reference", errorCode(t, resp.Err()))
+		assert.Contains(t, resp.Err().Error(), want)
+	}
+}
+
+func TestHandleToolKit_RefFailedDependency(t *testing.T) {
+	var mu sync.Mutex
+	files := map[string]string{}
+	tk := toolkit.New("flow_tk", createFileParent(t, files, &mu))
+
+	input := `{"name":"flow_tk","parents":[{"name":"operations","childs":[
+		{"name":"read_file","args":{"path":"missing.txt"}},
+		{"name":"write_file","args":{"path":"b.txt","content":{"$ref":"operations.read_file#0/content"}}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	childs := resp.Responses[0].ChildsResponses
+	assert.Equal(t, "file_not_found", errorCode(t, childs[0].Err()))
+	assert.Equal(t, "unresolved_reference", errorCode(t, childs[1].Err()))
+	assert.Contains(t, childs[1].Err().Error(), "operations.read_file#0' failed")
+	assert.NotContains(t, files, "b.txt", "Dependents of failed children must not run")
+}
+
+func TestHandleToolKit_RefCycle(t *testing.T) {
+	var mu sync.Mutex
+	tk := toolkit.New("flow_tk", createFileParent(t, map[string]string{"a.txt": "hello"}, &mu))
+
+	input := `{"name":"flow_tk","parents":[{"name":"operations","childs":[
+		{"name":"write_file","args":{"path":{"$ref":"operations.write_file#1/path"}}},
+		{"name":"write_file","args":{"path":{"$ref":"operations.write_file#0/path"}}},
+		{"name":"read_file","args":{"path":"a.txt"}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	childs := resp.Responses[0].ChildsResponses
+	require.Len(t, childs, 3)
+	assert.Equal(t, "reference_cycle", errorCode(t, childs[0].Err()))
+	assert.Equal(t, "reference_cycle", errorCode(t, childs[1].Err()))
+	assert.Contains(t, childs[0].Err().Error(), "operations.write_file#0, operations.write_file#1")
+	assert.Equal(t, fileResult{Path: "a.txt", Content: "hello"}, childs[2].Result, "Children outside the cycle should still run")
+}
+
+func TestHandleToolKit_RefConcurrentWaitsForDependencies(t *testing.T) {
+	slow := toolkit.NewChild("slow", "desc_slow", func(ctx context.Context, args testArgs) (interface{}, error) {
+		time.Sleep(100 * time.Millisecond)
+		return testResp{Res: "slow:" + args.Val}, nil
+	})
+	parent := createTestParent(t, "p1", slow, createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1b", "r1b", false))
+	tk := toolkit.NewWithOptions("flow_tk", []toolkit.Option{toolkit.WithExecutionMode(toolkit.ExecutionConcurrent)}, parent)
+
+	input := `{"name":"flow_tk","parents":[{"name":"p1","childs":[
+		{"name":"c1a","args":{"val":{"$ref":"p1.slow#0/res"}}},
+		{"name":"slow","args":{"val":"x"}},
+		{"name":"c1b","args":{"val":"y"}}
+	]}]}`
+
+	var mu sync.Mutex
+	var finished []string
+	var indexes []int
+	resp, err := tk.HandleToolKitStream(context.Background(), json.RawMessage(input), func(ev toolkit.Event) {
+		if ev.Type == toolkit.EventChildFinished {
+			mu.Lock()
+			finished = append(finished, ev.Child)
+			indexes = append(indexes, ev.ChildIndex)
+			mu.Unlock()
+		}
+	})
+	require.NoError(t, err)
+
+	childs := resp.Responses[0].ChildsResponses
+	assert.Equal(t, testResp{Res: "r1a:slow:x"}, childs[0].Result)
+	assert.Equal(t, testResp{Res: "slow:x"}, childs[1].Result)
+	assert.Equal(t, testResp{Res: "r1b:y"}, childs[2].Result)
+	assert.Equal(t, []string{"c1b", "slow", "c1a"}, finished, "Independent children should not wait for the chain")
+	assert.Equal(t, []int{2, 1, 0}, indexes, "Events should report the position of the child in the request")
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Logging Test Helpers ---
+
+// newBufferLogger returns a debug-level JSON logger writing to buf.
+func newBufferLogger(buf *bytes.Buffer, opts *slog.HandlerOptions) *slog.Logger {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	opts.Level = slog.LevelDebug
+	return slog.New(slog.NewJSONHandler(buf, opts))
+}
+
+// logRecords decodes the JSON log records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+// findRecord returns the first record with the given message.
+func findRecord(t *testing.T, records []map[string]interface{}, msg string) map[string]interface{} {
+	t.Helper()
+	for _, record := range records {
+		if record["msg"] == msg {
+			return record
+		}
+	}
+	require.Failf(t, "log record not found", "no record with message %q in %v", msg, records)
+	return nil
+}
+
+// --- Test Logging ---
+
+func TestLogging_ConsistentAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	var handlerRecord bool
+	logging := toolkit.NewChild("logs", "desc_logs", func(ctx context.Context, args testArgs) (interface{}, error) {
+		toolkit.LoggerFromContext(ctx).Info("from handler")
+		handlerRecord = true
+		return testResp{Res: "ok"}, nil
+	})
+	parent := createTestParent(t, "p1", logging, createTestChildFn(t, "c1err", "", true))
+	tk := toolkit.NewWithOptions("log_tk", []toolkit.Option{toolkit.WithLogger(newBufferLogger(&buf, nil))}, parent)
+
+	ctx := toolkit.ContextWithCallID(context.Background(), "toolu_123")
+	input := `{"name":"log_tk","parents":[{"name":"p1","childs":[{"name":"logs","args":{"val":"x"}},{"name":"c1err","args":{}}]}]}`
+	_, err := tk.HandleToolKit(ctx, json.RawMessage(input))
+	require.NoError(t, err)
+	require.True(t, handlerRecord)
+
+	records := logRecords(t, &buf)
+	failed := findRecord(t, records, "Child failed")
+	assert.Equal(t, "WARN", failed["level"])
+	assert.Equal(t, "log_tk", failed[toolkit.LogKeyToolkit])
+	assert.Equal(t, "p1", failed[toolkit.LogKeyParent])
+	assert.Equal(t, "c1err", failed[toolkit.LogKeyChild])
+	assert.Equal(t, "toolu_123", failed[toolkit.LogKeyCallID])
+	assert.Equal(t, "handler_execution_error", failed[toolkit.LogKeyErrorCode])
+	assert.Contains(t, failed, toolkit.LogKeyDuration)
+
+	fromHandler := findRecord(t, records, "from handler")
+	assert.Equal(t, "logs", fromHandler[toolkit.LogKeyChild], "Handlers should log with the child attributes")
+	assert.Equal(t, "toolu_123", fromHandler[toolkit.LogKeyCallID])
+
+	started := findRecord(t, records, "Child started")
+	assert.Equal(t, float64(len(`{"val":"x"}`)), started[toolkit.LogKeyArgsBytes])
+	assert.NotContains(t, started, "args", "Arguments should only be logged by size")
+	assert.Equal(t, "DEBUG", started["level"])
+}
+
+func TestLogging_RedactAndSilence(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newBufferLogger(&buf, &slog.HandlerOptions{ReplaceAttr: toolkit.RedactLogAttrs(toolkit.LogKeyError)})
+	tk := toolkit.NewWithOptions("log_tk", []toolkit.Option{toolkit.WithLogger(logger)},
+		createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false)))
+
+	input := `{"name":"log_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"secret"}},{"name":"c1a","args":{"val":["secret"]}}]}]}`
+	_, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "secret", "Neither arguments nor error messages should be logged")
+	assert.Equal(t, "[REDACTED]", findRecord(t, logRecords(t, &buf), "Child failed")[toolkit.LogKeyError])
+	assert.NotEmpty(t, findRecord(t, logRecords(t, &buf), "Child started")[toolkit.LogKeyCallID], "A call ID should be generated")
+
+	// A nil logger silences the toolkit, including registration warnings
+	var defaultBuf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newBufferLogger(&defaultBuf, nil))
+	defer slog.SetDefault(previous)
+
+	silent := toolkit.NewWithOptions("log_tk", []toolkit.Option{toolkit.WithLogger(nil)}, nil,
+		createTestParent(t, "p1", createTestChildFn(t, "c1err", "", true)))
+	_, err = silent.HandleToolKit(context.Background(), json.RawMessage(`{"name":"log_tk","parents":[{"name":"p1","childs":[{"name":"c1err","args":{}}]}]}`))
+	require.NoError(t, err)
+	assert.Empty(t, defaultBuf.String())
+}
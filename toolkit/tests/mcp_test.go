@@ -0,0 +1,553 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+	"github.com/h-ess/ai-toolkit/toolkit/mcp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Test Helpers ---
+
+func createMCPTestToolkit(t *testing.T) *toolkit.Toolkit {
+	t.Helper()
+	blocking := toolkit.NewChild("block", "desc_block", func(ctx context.Context, args testArgs) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	return toolkit.New("mcp_tk",
+		createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true), blocking),
+	)
+}
+
+// stdioClient drives a Server over in-memory pipes.
+type stdioClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Scanner
+	done   chan error
+	nextID int
+}
+
+func startStdioServer(t *testing.T, server *mcp.Server) *stdioClient {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &stdioClient{t: t, in: inW, out: bufio.NewScanner(outR), done: make(chan error, 1)}
+	go func() {
+		err := server.Serve(context.Background(), inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		select {
+		case err := <-c.done:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Error("Serve did not return after stdin was closed")
+		}
+	})
+	return c
+}
+
+func (c *stdioClient) send(msg string) {
+	c.t.Helper()
+	_, err := io.WriteString(c.in, msg+"\n")
+	require.NoError(c.t, err)
+}
+
+func (c *stdioClient) receive() map[string]interface{} {
+	c.t.Helper()
+	require.True(c.t, c.out.Scan(), "Expected a response from the server")
+	var resp map[string]interface{}
+	require.NoError(c.t, json.Unmarshal(c.out.Bytes(), &resp))
+	return resp
+}
+
+func (c *stdioClient) call(method string, params interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.nextID++
+	raw, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	c.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, c.nextID, method, raw))
+	resp := c.receive()
+	assert.Equal(c.t, float64(c.nextID), resp["id"])
+	return resp
+}
+
+// toolResult extracts the result of a tools/call response and its decoded text content.
+func toolResult(t *testing.T, resp map[string]interface{}) (map[string]interface{}, interface{}) {
+	t.Helper()
+	require.Contains(t, resp, "result", "Expected a result, got %v", resp)
+	result := resp["result"].(map[string]interface{})
+	content := result["content"].([]interface{})
+	require.Len(t, content, 1)
+	block := content[0].(map[string]interface{})
+	assert.Equal(t, "text", block["type"])
+	var decoded interface{}
+	require.NoError(t, json.Unmarshal([]byte(block["text"].(string)), &decoded))
+	return result, decoded
+}
+
+// --- Test MCP Server over stdio ---
+
+func TestMCPServer_Stdio_InitializeAndList(t *testing.T) {
+	c := startStdioServer(t, mcp.NewServer(createMCPTestToolkit(t), mcp.WithServerInfo("test_server", "1.2.3")))
+
+	resp := c.call("initialize", map[string]interface{}{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "test", "version": "0"},
+	})
+	result := resp["result"].(map[string]interface{})
+	assert.Equal(t, "2025-03-26", result["protocolVersion"], "A supported client version should be echoed")
+	assert.Equal(t, map[string]interface{}{"name": "test_server", "version": "1.2.3"}, result["serverInfo"])
+	assert.Contains(t, result["capabilities"], "tools")
+
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	resp = c.call("ping", nil)
+	assert.Equal(t, map[string]interface{}{}, resp["result"])
+
+	resp = c.call("tools/list", map[string]interface{}{})
+	tools := resp["result"].(map[string]interface{})["tools"].([]interface{})
+	names := []string{}
+	for _, tool := range tools {
+		tm := tool.(map[string]interface{})
+		names = append(names, tm["name"].(string))
+		assert.Equal(t, "object", tm["inputSchema"].(map[string]interface{})["type"])
+	}
+	assert.Equal(t, []string{"mcp_tk", "p1__block", "p1__c1a", "p1__c1err"}, names)
+
+	resp = c.call("resources/list", nil)
+	assert.Equal(t, float64(-32601), resp["error"].(map[string]interface{})["code"])
+}
+
+func TestMCPServer_Stdio_ListChanged(t *testing.T) {
+	tk := createMCPTestToolkit(t)
+	c := startStdioServer(t, mcp.NewServer(tk, mcp.WithToolMode(mcp.ToolModeFlat)))
+
+	resp := c.call("initialize", map[string]interface{}{"protocolVersion": "2025-03-26"})
+	capabilities := resp["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"listChanged": true}, capabilities["tools"])
+
+	require.NoError(t, tk.AddChild("p1", createTestChildFn(t, "added", "ra", false)))
+	assert.Equal(t, map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/tools/list_changed"}, c.receive())
+
+	resp = c.call("tools/list", map[string]interface{}{})
+	tools := resp["result"].(map[string]interface{})["tools"].([]interface{})
+	assert.Equal(t, "p1__added", tools[0].(map[string]interface{})["name"])
+}
+
+// closingWriter records writes made after it was closed.
+type closingWriter struct {
+	mu         sync.Mutex
+	closed     bool
+	lateWrites int
+}
+
+func (w *closingWriter) Write(p []byte) (int, error) {
+	time.Sleep(100 * time.Microsecond) // Widen the window of a write racing with Serve returning
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		w.lateWrites++
+	}
+	return len(p), nil
+}
+
+func (w *closingWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+}
+
+func TestMCPServer_Stdio_NoWritesAfterServeReturns(t *testing.T) {
+	tk := toolkit.NewWithOptions("mcp_tk", []toolkit.Option{toolkit.WithLogger(nil)}, createTestParent(t, "p1"))
+	server := mcp.NewServer(tk)
+
+	stop := make(chan struct{})
+	changing := make(chan struct{})
+	go func() {
+		defer close(changing)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = tk.AddChild("p1", createTestChildFn(t, "added", "ra", false))
+				time.Sleep(50 * time.Microsecond)
+			}
+		}
+	}()
+
+	writers := make([]*closingWriter, 0, 20)
+	for i := 0; i < 20; i++ {
+		w := &closingWriter{}
+		require.NoError(t, server.Serve(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n"), w))
+		w.close()
+		writers = append(writers, w)
+	}
+	close(stop)
+	<-changing
+
+	for _, w := range writers {
+		w.mu.Lock()
+		assert.Zero(t, w.lateWrites, "Serve should not write after it returned")
+		w.mu.Unlock()
+	}
+}
+
+func TestMCPServer_Stdio_CallTool(t *testing.T) {
+	c := startStdioServer(t, mcp.NewServer(createMCPTestToolkit(t)))
+
+	// Flat tool
+	result, decoded := toolResult(t, c.call("tools/call", map[string]interface{}{
+		"name": "p1__c1a", "arguments": map[string]interface{}{"val": "v"},
+	}))
+	assert.NotContains(t, result, "isError")
+	assert.Equal(t, map[string]interface{}{"res": "r1a:v"}, decoded)
+	assert.Equal(t, decoded, result["structuredContent"])
+
+	// Hierarchical tool
+	result, decoded = toolResult(t, c.call("tools/call", map[string]interface{}{
+		"name": "mcp_tk",
+		"arguments": map[string]interface{}{"name": "mcp_tk", "parents": []interface{}{
+			map[string]interface{}{"name": "p1", "childs": []interface{}{
+				map[string]interface{}{"name": "c1a", "args": map[string]interface{}{"val": "h"}},
+			}},
+		}},
+	}))
+	assert.NotContains(t, result, "isError")
+	child := decoded.(map[string]interface{})["responses"].([]interface{})[0].(map[string]interface{})["childsResponses"].([]interface{})[0]
+	assert.Equal(t, "ok", child.(map[string]interface{})["status"])
+	assert.Equal(t, map[string]interface{}{"res": "r1a:h"}, child.(map[string]interface{})["result"])
+
+	// A hierarchical call with a failed child is flagged, but keeps the other results
+	result, decoded = toolResult(t, c.call("tools/call", map[string]interface{}{
+		"name": "mcp_tk",
+		"arguments": map[string]interface{}{"name": "mcp_tk", "parents": []interface{}{
+			map[string]interface{}{"name": "p1", "childs": []interface{}{
+				map[string]interface{}{"name": "c1a", "args": map[string]interface{}{"val": "h"}},
+				map[string]interface{}{"name": "c1err", "args": map[string]interface{}{}},
+			}},
+		}},
+	}))
+	assert.Equal(t, true, result["isError"])
+	children := decoded.(map[string]interface{})["responses"].([]interface{})[0].(map[string]interface{})["childsResponses"].([]interface{})
+	require.Len(t, children, 2)
+	assert.Equal(t, "ok", children[0].(map[string]interface{})["status"])
+
+	// Tool failures become error results built from the ToolKitError
+	result, decoded = toolResult(t, c.call("tools/call", map[string]interface{}{"name": "p1__c1err"}))
+	assert.Equal(t, true, result["isError"])
+	assert.Equal(t, "handler_execution_error", decoded.(map[string]interface{})["Code"])
+
+	result, decoded = toolResult(t, c.call("tools/call", map[string]interface{}{
+		"name": "p1__c1a", "arguments": map[string]interface{}{"val": 1},
+	}))
+	assert.Equal(t, true, result["isError"])
+	assert.Equal(t, "invalid_arguments", decoded.(map[string]interface{})["Code"])
+	assert.NotEmpty(t, decoded.(map[string]interface{})["Violations"])
+
+	// Unknown tools are protocol errors
+	resp := c.call("tools/call", map[string]interface{}{"name": "p1__missing"})
+	assert.Equal(t, float64(-32602), resp["error"].(map[string]interface{})["code"])
+}
+
+func TestMCPServer_Stdio_Cancellation(t *testing.T) {
+	c := startStdioServer(t, mcp.NewServer(createMCPTestToolkit(t)))
+
+	c.send(`{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"p1__block","arguments":{}}}`)
+	// Other requests are answered while the tool call is still running
+	c.send(`{"jsonrpc":"2.0","id":"ping","method":"ping"}`)
+	assert.Equal(t, "ping", c.receive()["id"])
+
+	c.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow"}}`)
+	resp := c.receive()
+	assert.Equal(t, "slow", resp["id"])
+	result, decoded := toolResult(t, resp)
+	assert.Equal(t, true, result["isError"])
+	assert.Equal(t, "canceled", decoded.(map[string]interface{})["Code"])
+}
+
+func TestMCPServer_Stdio_MalformedInput(t *testing.T) {
+	c := startStdioServer(t, mcp.NewServer(createMCPTestToolkit(t)))
+
+	c.send(`{not json`)
+	resp := c.receive()
+	assert.Nil(t, resp["id"])
+	assert.Equal(t, float64(-32700), resp["error"].(map[string]interface{})["code"])
+
+	c.send(`{"jsonrpc":"1.0","id":7,"method":"ping"}`)
+	resp = c.receive()
+	assert.Equal(t, float64(-32600), resp["error"].(map[string]interface{})["code"])
+}
+
+// --- Test MCP Server over HTTP ---
+
+func TestMCPServer_HTTP(t *testing.T) {
+	ts := httptest.NewServer(mcp.NewServer(createMCPTestToolkit(t),
+		mcp.WithToolMode(mcp.ToolModeHierarchical),
+		mcp.WithAllowedOrigins("http://localhost:3000"),
+	))
+	defer ts.Close()
+
+	post := func(body, origin string) (*http.Response, map[string]interface{}) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var decoded map[string]interface{}
+		if len(bytes.TrimSpace(raw)) > 0 && raw[0] == '{' {
+			require.NoError(t, json.Unmarshal(raw, &decoded))
+		}
+		return resp, decoded
+	}
+
+	resp, body := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	tools := body["result"].(map[string]interface{})["tools"].([]interface{})
+	require.Len(t, tools, 1, "Hierarchical mode should only list the toolkit tool")
+	assert.Equal(t, "mcp_tk", tools[0].(map[string]interface{})["name"])
+
+	resp, body = post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"p1__c1a","arguments":{}}}`, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(-32602), body["error"].(map[string]interface{})["code"], "Flat tools are not exposed in hierarchical mode")
+
+	resp, _ = post(`{"jsonrpc":"2.0","method":"notifications/initialized"}`, "")
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, _ = post(`{"jsonrpc":"2.0","id":3,"method":"ping"}`, "http://localhost:3000")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = post(`{"jsonrpc":"2.0","id":4,"method":"ping"}`, "http://evil.example")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, _ = post(`{broken`, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	getResp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	getResp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, getResp.StatusCode)
+}
+
+// --- Test MCP Client Bridge ---
+
+// TestMCPHelperProcess is not a real test: it runs the MCP test toolkit as a stdio
+// server when started as a subprocess by TestMCPClient_DialCommand.
+func TestMCPHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_MCP_HELPER") != "1" {
+		return
+	}
+	_ = mcp.NewServer(createMCPTestToolkit(t)).ServeStdio(context.Background())
+	os.Exit(0)
+}
+
+// connectPipeClient connects a Client to an in-process Server over pipes.
+func connectPipeClient(t *testing.T, server *mcp.Server) *mcp.Client {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		_ = server.Serve(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	client, err := mcp.NewStdioClient(context.Background(), outR, inW)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestMCPClient_NewParent(t *testing.T) {
+	client := connectPipeClient(t, mcp.NewServer(createMCPTestToolkit(t), mcp.WithToolMode(mcp.ToolModeFlat), mcp.WithServerInfo("remote_server", "9.9.9")))
+	assert.Equal(t, "remote_server", client.ServerInfo().Name)
+
+	remote, err := mcp.NewParent(context.Background(), client, "remote", "Remote tools")
+	require.NoError(t, err)
+	assert.Equal(t, "remote", remote.GetName())
+
+	children := remote.GetChildren()
+	require.Len(t, children, 3)
+	require.Contains(t, children, "p1__c1a")
+	assert.Equal(t, "desc_c1a", children["p1__c1a"].GetDescription())
+
+	// The server-provided schema is passed through unchanged
+	raw, err := json.Marshal(children["p1__c1a"].GetInputSchema())
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+	assert.Contains(t, schema["properties"], "val")
+
+	// Native and remote tools mix in one toolkit
+	tk := toolkit.New("mixed_tk", createTestParent(t, "local", createTestChildFn(t, "l1", "rl1", false)), remote)
+	input := `{"name":"mixed_tk","parents":[
+		{"name":"local","childs":[{"name":"l1","args":{"val":"a"}}]},
+		{"name":"remote","childs":[
+			{"name":"p1__c1a","args":{"val":"b"}},
+			{"name":"p1__c1err","args":{}},
+			{"name":"p1__c1a","args":{"val":2}}
+		]}
+	]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, testResp{Res: "rl1:a"}, resp.Responses[0].ChildsResponses[0].Result)
+
+	remoteResponses := resp.Responses[1].ChildsResponses
+	require.Len(t, remoteResponses, 3)
+	out, err := json.Marshal(remoteResponses[0].Result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"res":"r1a:b"}`, string(out))
+
+	for i, code := range map[int]string{1: "handler_execution_error", 2: "invalid_arguments"} {
+		tkErr, ok := remoteResponses[i].Err().(toolkit.ToolKitError)
+		require.True(t, ok, "Expected a ToolKitError response, got %#v", remoteResponses[i])
+		assert.Equal(t, code, tkErr.Code, "Remote error codes should be preserved")
+	}
+
+	// The bridged toolkit can describe the remote tools
+	assert.NotEmpty(t, tk.GetFlatTools())
+	_, err = tk.GetToolkitSchema("anthropic")
+	assert.NoError(t, err)
+}
+
+func TestMCPClient_OutputSchema(t *testing.T) {
+	typed := toolkit.NewTypedChild("typed", "desc_typed", func(ctx context.Context, args testArgs) (testResp, error) {
+		return testResp{Res: "typed:" + args.Val}, nil
+	})
+	scalar := toolkit.NewTypedChild("scalar", "desc_scalar", func(ctx context.Context, args testArgs) (string, error) {
+		return args.Val, nil
+	})
+	tk := toolkit.New("typed_tk", createTestParent(t, "p", typed, scalar, createTestChildFn(t, "untyped", "r", false)))
+	client := connectPipeClient(t, mcp.NewServer(tk, mcp.WithToolMode(mcp.ToolModeFlat)))
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	outputs := map[string]json.RawMessage{}
+	for _, tool := range tools {
+		outputs[tool.Name] = tool.OutputSchema
+	}
+	assert.Contains(t, string(outputs["p__typed"]), `"res"`)
+	assert.Empty(t, outputs["p__scalar"], "Output schemas are only declared for object results")
+	assert.Empty(t, outputs["p__untyped"])
+
+	// Remote children expose the declared output schema
+	remote, err := mcp.NewParent(context.Background(), client, "remote", "Remote tools")
+	require.NoError(t, err)
+	children := remote.GetChildren()
+	assert.JSONEq(t, string(outputs["p__typed"]), string(toolkit.OutputSchemaOf(children["p__typed"]).(json.RawMessage)))
+	assert.Nil(t, toolkit.OutputSchemaOf(children["p__untyped"]))
+}
+
+func TestMCPClient_Timeout(t *testing.T) {
+	client := connectPipeClient(t, mcp.NewServer(createMCPTestToolkit(t), mcp.WithToolMode(mcp.ToolModeFlat)))
+	remote, err := mcp.NewParent(context.Background(), client, "remote", "Remote tools",
+		toolkit.WithParentTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	resp := remote.HandleChildren(context.Background(), []toolkit.ToolKitChild{{Name: "p1__block", Args: json.RawMessage(`{}`)}})
+	tkErr, ok := resp.ChildsResponses[0].Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected a ToolKitError response, got %#v", resp.ChildsResponses[0])
+	assert.Equal(t, "timeout", tkErr.Code)
+
+	// The connection stays usable after an abandoned call
+	result, err := client.CallTool(context.Background(), "p1__c1a", json.RawMessage(`{"val":"x"}`))
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+}
+
+func TestMCPClient_DialHTTP(t *testing.T) {
+	ts := httptest.NewServer(mcp.NewServer(createMCPTestToolkit(t)))
+	defer ts.Close()
+
+	client, err := mcp.DialHTTP(context.Background(), ts.URL, nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, tools, 4)
+
+	result, err := client.CallTool(context.Background(), "p1__c1a", json.RawMessage(`{"val":"h"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"res":"r1a:h"}`, result.Content[0].Text)
+
+	_, err = client.CallTool(context.Background(), "missing", nil)
+	var rpcErr *mcp.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32602, rpcErr.Code)
+}
+
+func TestMCPClient_DialHTTP_MultiLineEvents(t *testing.T) {
+	// Re-encode every JSON response as an event whose data spans one line per JSON line
+	server := mcp.NewServer(createMCPTestToolkit(t))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, r)
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+			w.WriteHeader(rec.Code)
+			_, _ = w.Write(rec.Body.Bytes())
+			return
+		}
+		var indented bytes.Buffer
+		require.NoError(t, json.Indent(&indented, bytes.TrimSpace(rec.Body.Bytes()), "", "  "))
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write([]byte("event: message\ndata: " + strings.ReplaceAll(indented.String(), "\n", "\ndata: ") + "\n\n"))
+	}))
+	defer ts.Close()
+
+	client, err := mcp.DialHTTP(context.Background(), ts.URL, nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.CallTool(context.Background(), "p1__c1a", json.RawMessage(`{"val":"multi\nline"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"res":"r1a:multi\nline"}`, result.Content[0].Text)
+}
+
+func TestMCPClient_DialCommand(t *testing.T) {
+	t.Setenv("GO_WANT_MCP_HELPER", "1")
+	client, err := mcp.DialCommand(context.Background(), os.Args[0], "-test.run=^TestMCPHelperProcess$")
+	require.NoError(t, err)
+
+	remote, err := mcp.NewParent(context.Background(), client, "remote", "Remote tools")
+	require.NoError(t, err)
+	assert.Contains(t, remote.GetChildren(), "mcp_tk")
+
+	resp := remote.HandleChildren(context.Background(), []toolkit.ToolKitChild{{Name: "p1__c1a", Args: json.RawMessage(`{"val":"sub"}`)}})
+	out, err := json.Marshal(resp.ChildsResponses[0].Result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"res":"r1a:sub"}`, string(out))
+
+	assert.NoError(t, client.Close())
+}
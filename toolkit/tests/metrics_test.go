@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+	tkprom "github.com/h-ess/ai-toolkit/toolkit/prometheus"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Metrics Test Helpers ---
+
+// metricsRecorder collects the observations reported to a toolkit.
+type metricsRecorder struct {
+	mu   sync.Mutex
+	obs  []toolkit.ChildObservation
+	hook toolkit.Metrics
+}
+
+func newMetricsRecorder() *metricsRecorder {
+	r := &metricsRecorder{}
+	r.hook = toolkit.MetricsFunc(func(obs toolkit.ChildObservation) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.obs = append(r.obs, obs)
+	})
+	return r
+}
+
+// byTool returns the observations indexed by "parent.child".
+func (r *metricsRecorder) byTool() map[string]toolkit.ChildObservation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]toolkit.ChildObservation, len(r.obs))
+	for _, obs := range r.obs {
+		out[obs.Parent+"."+obs.Child] = obs
+	}
+	return out
+}
+
+// --- Test Metrics ---
+
+func TestMetrics_Observations(t *testing.T) {
+	recorder := newMetricsRecorder()
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "ok", "r", false), createTestChildFn(t, "fail", "", true))
+	tk := toolkit.NewWithOptions("metrics_tk", []toolkit.Option{toolkit.WithMetrics(recorder.hook)}, parent1)
+
+	input := `{"name":"metrics_tk","parents":[
+		{"name":"p1","childs":[{"name":"ok","args":{"val":"x"}},{"name":"fail","args":{}},{"name":"ghost","args":{}}]},
+		{"name":"hallucinated","childs":[{"name":"c","args":{}}]}
+	]}`
+	_, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	obs := recorder.byTool()
+	require.Len(t, obs, 4)
+
+	ok := obs["p1.ok"]
+	assert.Equal(t, "metrics_tk", ok.Toolkit)
+	assert.Empty(t, ok.ErrorCode)
+	assert.Equal(t, len(`{"val":"x"}`), ok.ArgsSize)
+	assert.Equal(t, len(`{"res":"r:x"}`), ok.ResultSize)
+	assert.Positive(t, ok.Duration)
+
+	assert.Equal(t, "handler_execution_error", obs["p1.fail"].ErrorCode)
+	assert.Zero(t, obs["p1.fail"].ResultSize)
+	assert.Equal(t, "child_not_found", obs["p1.ghost"].ErrorCode)
+	assert.Equal(t, "parent_not_found", obs["hallucinated."].ErrorCode)
+
+	_, err = tk.HandleFlatTool(context.Background(), "p1__ghost", json.RawMessage(`{}`))
+	require.Error(t, err)
+	flat := recorder.byTool()[".p1__ghost"]
+	assert.Equal(t, "tool_not_found", flat.ErrorCode)
+	assert.Equal(t, "metrics_tk", flat.Toolkit)
+}
+
+func TestMetrics_PrometheusCollector(t *testing.T) {
+	collector := tkprom.NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "ok", "r", false), createTestChildFn(t, "fail", "", true))
+	tk := toolkit.NewWithOptions("prom_tk", []toolkit.Option{toolkit.WithMetrics(collector)}, parent1)
+
+	input := `{"name":"prom_tk","parents":[
+		{"name":"p1","childs":[{"name":"ok","args":{"val":"x"}},{"name":"ok","args":{"val":"y"}},{"name":"fail","args":{}},{"name":"ghost","args":{}}]},
+		{"name":"hallucinated","childs":[{"name":"c","args":{}}]}
+	]}`
+	_, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	_, err = tk.HandleFlatTool(context.Background(), "p1__hallucinated", json.RawMessage(`{}`))
+	require.Error(t, err)
+
+	expected := `
+# HELP toolkit_child_invocations_total Number of child tool invocations.
+# TYPE toolkit_child_invocations_total counter
+toolkit_child_invocations_total{child="fail",parent="p1",status="error",toolkit="prom_tk"} 1
+toolkit_child_invocations_total{child="ok",parent="p1",status="ok",toolkit="prom_tk"} 2
+# HELP toolkit_child_errors_total Number of failed child tool invocations by ToolKitError code.
+# TYPE toolkit_child_errors_total counter
+toolkit_child_errors_total{child="fail",code="handler_execution_error",parent="p1",toolkit="prom_tk"} 1
+# HELP toolkit_unknown_tools_total Number of requests for parents, children or flat tools that do not exist.
+# TYPE toolkit_unknown_tools_total counter
+toolkit_unknown_tools_total{kind="child",parent="p1",toolkit="prom_tk"} 1
+toolkit_unknown_tools_total{kind="parent",parent="",toolkit="prom_tk"} 1
+toolkit_unknown_tools_total{kind="tool",parent="",toolkit="prom_tk"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"toolkit_child_invocations_total", "toolkit_child_errors_total", "toolkit_unknown_tools_total"))
+
+	// Histograms are observed for every known child; result sizes only on success
+	count, err := testutil.GatherAndCount(registry, "toolkit_child_duration_seconds", "toolkit_child_args_bytes", "toolkit_child_result_bytes")
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Middleware Test Helpers ---
+
+// customChild is a Child implemented without the builder.
+type customChild struct{}
+
+func (customChild) GetName() string             { return "custom" }
+func (customChild) GetDescription() string      { return "desc_custom" }
+func (customChild) GetInputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (customChild) Handle(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	return "custom:" + string(args), nil
+}
+
+// tracingMiddleware records "<label>:<parent>.<child>" for every call it sees.
+func tracingMiddleware(label string, mu *sync.Mutex, trace *[]string) toolkit.Middleware {
+	return func(next toolkit.Handler) toolkit.Handler {
+		return func(ctx context.Context, call toolkit.ChildCall) (interface{}, error) {
+			mu.Lock()
+			*trace = append(*trace, label+":"+call.Parent+"."+call.Child)
+			mu.Unlock()
+			return next(ctx, call)
+		}
+	}
+}
+
+// --- Test Middleware ---
+
+func TestMiddleware_Order(t *testing.T) {
+	var mu sync.Mutex
+	var trace []string
+	mw := func(label string) toolkit.Middleware { return tracingMiddleware(label, &mu, &trace) }
+
+	builderChild := toolkit.NewChild("c1a", "desc_c1a", func(ctx context.Context, args testArgs) (interface{}, error) {
+		return testResp{Res: args.Val}, nil
+	}, toolkit.WithChildMiddleware(mw("child1"), mw("child2")))
+	parent := toolkit.NewParentWithOptions("p1", "desc_p1",
+		[]toolkit.ParentOption{toolkit.WithParentMiddleware(mw("parent"))},
+		builderChild, toolkit.WrapChild(customChild{}, mw("wrapped")))
+	tk := toolkit.NewWithOptions("mw_tk", []toolkit.Option{toolkit.WithMiddleware(mw("toolkit1"), mw("toolkit2"))}, parent)
+
+	input := `{"name":"mw_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"x"}},{"name":"custom","args":{"k":1}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, testResp{Res: "x"}, resp.Responses[0].ChildsResponses[0].Result)
+	assert.Equal(t, `custom:{"k":1}`, resp.Responses[0].ChildsResponses[1].Result)
+	assert.Equal(t, []string{
+		"toolkit1:p1.c1a", "toolkit2:p1.c1a", "parent:p1.c1a", "child1:p1.c1a", "child2:p1.c1a",
+		"toolkit1:p1.custom", "toolkit2:p1.custom", "parent:p1.custom", "wrapped:p1.custom",
+	}, trace)
+}
+
+func TestMiddleware_RewritesArgsAndShortCircuits(t *testing.T) {
+	var calls int
+	child := toolkit.NewChild("c1a", "desc_c1a", func(ctx context.Context, args testArgs) (interface{}, error) {
+		calls++
+		return testResp{Res: args.Val}, nil
+	})
+
+	cache := map[string]interface{}{`{"val":"cached"}`: testResp{Res: "from-cache"}}
+	caching := func(next toolkit.Handler) toolkit.Handler {
+		return func(ctx context.Context, call toolkit.ChildCall) (interface{}, error) {
+			if result, ok := cache[string(call.Args)]; ok {
+				return result, nil
+			}
+			return next(ctx, call)
+		}
+	}
+	upper := func(next toolkit.Handler) toolkit.Handler {
+		return func(ctx context.Context, call toolkit.ChildCall) (interface{}, error) {
+			call.Args = json.RawMessage(`{"val":"REWRITTEN"}`)
+			return next(ctx, call)
+		}
+	}
+	tk := toolkit.NewWithOptions("mw_tk", []toolkit.Option{toolkit.WithMiddleware(caching, upper)}, createTestParent(t, "p1", child))
+
+	input := `{"name":"mw_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"cached"}},{"name":"c1a","args":{"val":"x"}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, testResp{Res: "from-cache"}, resp.Responses[0].ChildsResponses[0].Result)
+	assert.Equal(t, testResp{Res: "REWRITTEN"}, resp.Responses[0].ChildsResponses[1].Result)
+	assert.Equal(t, 1, calls, "Short-circuited calls must not reach the handler")
+}
+
+func TestMiddleware_ErrorsAreChildResponses(t *testing.T) {
+	deny := func(next toolkit.Handler) toolkit.Handler {
+		return func(ctx context.Context, call toolkit.ChildCall) (interface{}, error) {
+			return nil, toolkit.NewError("unauthorized", "Access to "+call.Child+" denied")
+		}
+	}
+	parent := toolkit.NewParentWithOptions("p1", "desc_p1", []toolkit.ParentOption{toolkit.WithParentMiddleware(deny)},
+		createTestChildFn(t, "c1a", "r1a", false))
+	resp, err := toolkit.New("mw_tk", parent).HandleToolKit(context.Background(),
+		json.RawMessage(`{"name":"mw_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"x"}}]}]}`))
+	require.NoError(t, err)
+
+	tkErr, ok := resp.Responses[0].ChildsResponses[0].Err().(toolkit.ToolKitError)
+	require.True(t, ok)
+	assert.Equal(t, "unauthorized", tkErr.Code)
+}
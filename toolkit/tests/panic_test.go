@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Panic Test Helpers ---
+
+// panickingChild is a Child implemented without the builder whose Handle panics.
+type panickingChild struct{}
+
+func (panickingChild) GetName() string             { return "custom_boom" }
+func (panickingChild) GetDescription() string      { return "desc_custom_boom" }
+func (panickingChild) GetInputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (panickingChild) Handle(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	var m map[string]string
+	m["key"] = "value" // Assignment to a nil map
+	return nil, nil
+}
+
+func createPanicToolkit(t *testing.T, opts ...toolkit.Option) *toolkit.Toolkit {
+	t.Helper()
+	boom := toolkit.NewChild("boom", "desc_boom", func(ctx context.Context, args testArgs) (interface{}, error) {
+		panic("boom: " + args.Val)
+	})
+	parent := createTestParent(t, "p1", createTestChildFn(t, "ok", "r", false), boom, panickingChild{})
+	return toolkit.NewWithOptions("panic_tk", opts, parent)
+}
+
+const panicInput = `{"name":"panic_tk","parents":[{"name":"p1","childs":[
+	{"name":"ok","args":{"val":"a"}},
+	{"name":"boom","args":{"val":"b"}},
+	{"name":"custom_boom","args":{}},
+	{"name":"ok","args":{"val":"c"}}
+]}]}`
+
+// --- Test Panic Recovery ---
+
+func TestPanicRecovery_IsolatesChildren(t *testing.T) {
+	modes := map[string]toolkit.ExecutionMode{"sequential": toolkit.ExecutionSequential, "concurrent": toolkit.ExecutionConcurrent}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			resp, err := createPanicToolkit(t, toolkit.WithExecutionMode(mode)).HandleToolKit(context.Background(), json.RawMessage(panicInput))
+			require.NoError(t, err)
+			assert.Equal(t, [][]string{{"ok", "handler_panic", "handler_panic", "ok"}}, childCodes(t, resp))
+
+			children := resp.Responses[0].ChildsResponses
+			assert.Equal(t, testResp{Res: "r:c"}, children[3].Result, "Children after the panic should complete normally")
+
+			boomErr := children[1].Error
+			assert.Equal(t, "Tool 'boom' panicked: boom: b", boomErr.Message)
+			assert.Equal(t, "boom: b", boomErr.Details()["panic"])
+			stack := boomErr.Details()["stack"].(string)
+			assert.Contains(t, stack, "panic_test.go", "The stack should start at the panicking handler")
+			assert.NotContains(t, stack, "runtime/debug.Stack")
+
+			assert.Contains(t, children[2].Error.Message, "assignment to entry in nil map")
+		})
+	}
+}
+
+func TestPanicRecovery_DirectHandle(t *testing.T) {
+	child := toolkit.NewChild("boom", "desc_boom", func(ctx context.Context, args testArgs) (interface{}, error) {
+		panic("direct")
+	})
+	_, err := child.Handle(context.Background(), json.RawMessage(`{}`))
+	assert.ErrorIs(t, err, toolkit.ToolKitError{Code: toolkit.CodeHandlerPanic})
+}
+
+func TestPanicRecovery_LoggedAndObserved(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := newMetricsRecorder()
+	tk := createPanicToolkit(t, toolkit.WithLogger(newBufferLogger(&buf, nil)), toolkit.WithMetrics(recorder.hook))
+	_, err := tk.HandleToolKit(context.Background(), json.RawMessage(panicInput))
+	require.NoError(t, err)
+
+	record := findRecord(t, logRecords(t, &buf), "Child panicked")
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "boom", record[toolkit.LogKeyChild])
+	assert.Equal(t, "boom: b", record[toolkit.LogKeyPanic])
+	assert.NotEmpty(t, record[toolkit.LogKeyStack])
+
+	assert.Equal(t, toolkit.CodeHandlerPanic, recorder.byTool()["p1.boom"].ErrorCode)
+}
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Policy Test Helpers ---
+
+// createRollbackChild returns a child that records its rollbacks in rolledBack.
+// Rolling back the argument "broken" fails and rolling back "panic" panics.
+func createRollbackChild(t *testing.T, name string, mu *sync.Mutex, rolledBack *[]string) toolkit.Child {
+	t.Helper()
+	handler := func(ctx context.Context, args testArgs) (interface{}, error) {
+		return testResp{Res: name + ":" + args.Val}, nil
+	}
+	return toolkit.NewChild(name, "desc_"+name, handler,
+		toolkit.WithRollback(func(ctx context.Context, args testArgs, result interface{}) error {
+			if args.Val == "broken" {
+				return errors.New("cannot undo")
+			}
+			if args.Val == "panic" {
+				panic("undo exploded")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			*rolledBack = append(*rolledBack, args.Val+"="+result.(testResp).Res)
+			return nil
+		}))
+}
+
+const policyInput = `{"name":"policy_tk","parents":[
+	{"name":"p1","childs":[{"name":"c1a","args":{"val":"a"}},{"name":"c1err","args":{}},{"name":"c1a","args":{"val":"b"}}]},
+	{"name":"p2","childs":[{"name":"c2a","args":{"val":"c"}}]}
+]}`
+
+func createPolicyToolkit(t *testing.T, opts ...toolkit.Option) *toolkit.Toolkit {
+	t.Helper()
+	p1 := createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true))
+	p2 := createTestParent(t, "p2", createTestChildFn(t, "c2a", "r2a", false))
+	return toolkit.NewWithOptions("policy_tk", opts, p1, p2)
+}
+
+// --- Test Execution Policies ---
+
+func TestExecutionPolicy_ContinueIsDefault(t *testing.T) {
+	resp, err := createPolicyToolkit(t).HandleToolKit(context.Background(), json.RawMessage(policyInput))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ok", "handler_execution_error", "ok"}, {"ok"}}, childCodes(t, resp))
+}
+
+func TestExecutionPolicy_StopOnFirstError(t *testing.T) {
+	tk := createPolicyToolkit(t, toolkit.WithExecutionPolicy(toolkit.PolicyStopOnFirstError))
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(policyInput))
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{"ok", "handler_execution_error", "skipped"}, {"skipped"}}, childCodes(t, resp))
+	assert.Equal(t, "c2a", resp.Responses[1].ChildsResponses[0].Name)
+	assert.Contains(t, resp.Responses[1].ChildsResponses[0].Err().Error(), "'p1.c1err' failed")
+}
+
+func TestExecutionPolicy_StopOnFirstErrorConcurrent(t *testing.T) {
+	tk := createPolicyToolkit(t,
+		toolkit.WithExecutionMode(toolkit.ExecutionConcurrent),
+		toolkit.WithMaxConcurrency(1),
+		toolkit.WithExecutionPolicy(toolkit.PolicyStopOnFirstError),
+	)
+	input := `{"name":"policy_tk","parents":[{"name":"p1","childs":[{"name":"c1err","args":{}},{"name":"c1a","args":{"val":"a"}},{"name":"c1a","args":{"val":"b"}}]}]}`
+
+	// Goroutines may take the single slot in any order, so only the outcome of children
+	// started after the failure is deterministic.
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	codes := childCodes(t, resp)[0]
+	assert.Equal(t, "handler_execution_error", codes[0])
+	for _, code := range codes[1:] {
+		assert.Contains(t, []string{"ok", "skipped"}, code)
+	}
+}
+
+func TestExecutionPolicy_RequestOverride(t *testing.T) {
+	tk := createPolicyToolkit(t, toolkit.WithExecutionPolicy(toolkit.PolicyStopOnFirstError))
+	ctx := toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{Policy: toolkit.PolicyContinue})
+	resp, err := tk.HandleToolKit(ctx, json.RawMessage(policyInput))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ok", "handler_execution_error", "ok"}, {"ok"}}, childCodes(t, resp))
+
+	ctx = toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{Policy: "sometimes"})
+	_, err = tk.HandleToolKit(ctx, json.RawMessage(policyInput))
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, "unknown_execution_policy", tkErr.Code)
+}
+
+func TestExecutionPolicy_AllOrNothingRollsBack(t *testing.T) {
+	var mu sync.Mutex
+	var rolledBack []string
+	files := createTestParent(t, "files",
+		createRollbackChild(t, "write", &mu, &rolledBack),
+		createTestChildFn(t, "plain", "rp", false),
+		createTestChildFn(t, "fail", "", true),
+	)
+	tk := toolkit.NewWithOptions("policy_tk", []toolkit.Option{toolkit.WithExecutionPolicy(toolkit.PolicyAllOrNothing)}, files)
+
+	input := `{"name":"policy_tk","parents":[{"name":"files","childs":[
+		{"name":"write","args":{"val":"a"}},
+		{"name":"plain","args":{"val":"x"}},
+		{"name":"write","args":{"val":"b"}},
+		{"name":"write","args":{"val":"broken"}},
+		{"name":"fail","args":{}},
+		{"name":"write","args":{"val":"c"}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{"rolled_back", "ok", "rolled_back", "rollback_failed", "handler_execution_error", "skipped"}}, childCodes(t, resp))
+	assert.Equal(t, testResp{Res: "rp:x"}, resp.Responses[0].ChildsResponses[1].Result, "Children without rollback keep their result")
+	assert.Contains(t, resp.Responses[0].ChildsResponses[3].Err().Error(), "cannot undo")
+	assert.Equal(t, []string{"b=write:b", "a=write:a"}, rolledBack, "Rollbacks should run in reverse completion order with typed args and results")
+}
+
+func TestExecutionPolicy_AllOrNothingRecoversRollbackPanics(t *testing.T) {
+	var mu sync.Mutex
+	var rolledBack []string
+	files := createTestParent(t, "files", createRollbackChild(t, "write", &mu, &rolledBack), createTestChildFn(t, "fail", "", true))
+	tk := toolkit.NewWithOptions("policy_tk", []toolkit.Option{toolkit.WithExecutionPolicy(toolkit.PolicyAllOrNothing)}, files)
+
+	input := `{"name":"policy_tk","parents":[{"name":"files","childs":[
+		{"name":"write","args":{"val":"a"}},
+		{"name":"write","args":{"val":"panic"}},
+		{"name":"fail","args":{}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{"rolled_back", "rollback_failed", "handler_execution_error"}}, childCodes(t, resp))
+	panicErr := resp.Responses[0].ChildsResponses[1].Error
+	assert.Contains(t, panicErr.Message, "undo exploded")
+	assert.Equal(t, "undo exploded", panicErr.Details()["panic"])
+	assert.NotEmpty(t, panicErr.Details()["stack"])
+	assert.Equal(t, []string{"a=write:a"}, rolledBack, "Rollbacks after a panicking one should still run")
+}
+
+func TestExecutionPolicy_AllOrNothingSuccessKeepsResults(t *testing.T) {
+	var mu sync.Mutex
+	var rolledBack []string
+	tk := toolkit.NewWithOptions("policy_tk", []toolkit.Option{toolkit.WithExecutionPolicy(toolkit.PolicyAllOrNothing)},
+		createTestParent(t, "files", createRollbackChild(t, "write", &mu, &rolledBack)))
+
+	input := `{"name":"policy_tk","parents":[{"name":"files","childs":[{"name":"write","args":{"val":"a"}},{"name":"write","args":{"val":"b"}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ok", "ok"}}, childCodes(t, resp))
+	assert.Empty(t, rolledBack)
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Registry Test Helpers ---
+
+// fixedParent is a Parent implemented without the builder, so its children cannot change.
+type fixedParent struct{}
+
+func (fixedParent) GetName() string                       { return "fixed" }
+func (fixedParent) GetDescription() string                { return "desc_fixed" }
+func (fixedParent) GetChildren() map[string]toolkit.Child { return map[string]toolkit.Child{} }
+func (fixedParent) HandleChildren(ctx context.Context, reqs []toolkit.ToolKitChild) toolkit.ParentResponse {
+	return toolkit.ParentResponse{Name: "fixed"}
+}
+
+// changeRecorder collects the registry changes of a toolkit as "kind parent.child".
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *changeRecorder) record(change toolkit.RegistryChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, fmt.Sprintf("%s %s.%s", change.Kind, change.Parent, change.Child))
+}
+
+// --- Test Registry ---
+
+func TestRegistry_AddAndRemove(t *testing.T) {
+	tk := toolkit.New("registry_tk", createTestParent(t, "p1", createTestChildFn(t, "c1", "r1", false)))
+	recorder := &changeRecorder{}
+	stop := tk.OnChange(recorder.record)
+
+	require.NoError(t, tk.AddParent(createTestParent(t, "p2")))
+	require.NoError(t, tk.AddChild("p2", createTestChildFn(t, "c2", "r2", false)))
+	input := `{"name":"registry_tk","parents":[{"name":"p2","childs":[{"name":"c2","args":{"val":"x"}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ok"}}, childCodes(t, resp))
+
+	require.NoError(t, tk.RemoveChild("p2", "c2"))
+	resp, err = tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"child_not_found"}}, childCodes(t, resp))
+
+	require.NoError(t, tk.RemoveParent("p2"))
+	resp, err = tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"parent_not_found"}}, childCodes(t, resp))
+	assert.Equal(t, []string{"p1"}, resp.Responses[0].ChildsResponses[0].Error.Details()["available"])
+
+	stop()
+	require.NoError(t, tk.AddParent(createTestParent(t, "p3")))
+	assert.Equal(t, []string{"parent_added p2.", "child_added p2.c2", "child_removed p2.c2", "parent_removed p2."}, recorder.changes)
+	assert.Contains(t, tk.GetToolkitDescription(), `<parent name="p3"`)
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	tk := toolkit.New("registry_tk", createTestParent(t, "p1"), fixedParent{})
+	recorder := &changeRecorder{}
+	tk.OnChange(recorder.record)
+
+	assert.Equal(t, "invalid_arguments", errorCode(t, tk.AddParent(nil)))
+	assert.Equal(t, "parent_not_found", errorCode(t, tk.RemoveParent("ghost")))
+	assert.Equal(t, "parent_not_found", errorCode(t, tk.AddChild("ghost", createTestChildFn(t, "c", "r", false))))
+	assert.Equal(t, "invalid_arguments", errorCode(t, tk.AddChild("p1", nil)))
+	assert.Equal(t, "child_not_found", errorCode(t, tk.RemoveChild("p1", "ghost")))
+	assert.Equal(t, "immutable_parent", errorCode(t, tk.AddChild("fixed", createTestChildFn(t, "c", "r", false))))
+	assert.Empty(t, recorder.changes, "Failed changes should not be reported")
+}
+
+func TestRegistry_GetChildrenReturnsSnapshot(t *testing.T) {
+	parent := createTestParent(t, "p1", createTestChildFn(t, "c1", "r1", false))
+	children := parent.GetChildren()
+	delete(children, "c1")
+	children["c2"] = createTestChildFn(t, "c2", "r2", false)
+
+	assert.Equal(t, []string{"c1"}, keys(parent.GetChildren()))
+	parent.(toolkit.MutableParent).AddChild(createTestChildFn(t, "c3", "r3", false))
+	assert.Len(t, children, 1, "Snapshots should not change with the parent")
+}
+
+func TestRegistry_ConcurrentChanges(t *testing.T) {
+	tk := toolkit.NewWithOptions("registry_tk", []toolkit.Option{toolkit.WithExecutionMode(toolkit.ExecutionConcurrent)},
+		createTestParent(t, "p1", createTestChildFn(t, "c1", "r1", false)))
+	input := `{"name":"registry_tk","parents":[
+		{"name":"p1","childs":[{"name":"c1","args":{}},{"name":"dyn","args":{}}]},
+		{"name":"p2","childs":[{"name":"c2","args":{}}]}
+	]}`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+				assert.NoError(t, err)
+				assert.Equal(t, toolkit.StatusOK, resp.Responses[0].ChildsResponses[0].Status)
+				_ = tk.GetToolkitDescription()
+			}
+		}()
+	}
+	for j := 0; j < 50; j++ {
+		require.NoError(t, tk.AddChild("p1", createTestChildFn(t, "dyn", "rd", false)))
+		require.NoError(t, tk.AddParent(createTestParent(t, "p2", createTestChildFn(t, "c2", "r2", false))))
+		require.NoError(t, tk.RemoveChild("p1", "dyn"))
+		require.NoError(t, tk.RemoveParent("p2"))
+	}
+	wg.Wait()
+}
+
+func keys[T any](m map[string]T) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Retry Test Helpers ---
+
+// fastRetry retries handler errors without noticeable backoff.
+var fastRetry = toolkit.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	RetryIf:        toolkit.RetryOnCodes(toolkit.CodeHandlerExecutionError),
+}
+
+// flakyHandler fails until it has been called succeedOn times and counts its calls.
+func flakyHandler(calls *atomic.Int32, succeedOn int32) func(ctx context.Context, args testArgs) (interface{}, error) {
+	return func(ctx context.Context, args testArgs) (interface{}, error) {
+		if calls.Add(1) < succeedOn {
+			return nil, errors.New("connection reset")
+		}
+		return testResp{Res: "fetched:" + args.Val}, nil
+	}
+}
+
+// runRetryToolkit executes a single call of the child "fetch" of the parent "net".
+func runRetryToolkit(t *testing.T, parent toolkit.Parent) toolkit.ChildResponse {
+	t.Helper()
+	tk := toolkit.New("retry_tk", parent)
+	input := `{"name":"retry_tk","parents":[{"name":"net","childs":[{"name":"fetch","args":{"val":"a"}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+	return resp.Responses[0].ChildsResponses[0]
+}
+
+// --- Test Retries ---
+
+func TestRetry_SucceedsAfterTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	child := toolkit.NewChild("fetch", "desc_fetch", flakyHandler(&calls, 3), toolkit.WithRetry(fastRetry))
+
+	resp := runRetryToolkit(t, createTestParent(t, "net", child))
+	assert.Equal(t, toolkit.StatusOK, resp.Status)
+	assert.Equal(t, testResp{Res: "fetched:a"}, resp.Result)
+	assert.Equal(t, 3, resp.Attempts)
+	assert.EqualValues(t, 3, calls.Load())
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	policy := fastRetry
+	policy.MaxAttempts = 2
+	child := toolkit.NewChild("fetch", "desc_fetch", flakyHandler(&calls, 5), toolkit.WithRetry(policy))
+
+	resp := runRetryToolkit(t, createTestParent(t, "net", child))
+	assert.Equal(t, toolkit.StatusError, resp.Status)
+	assert.Equal(t, toolkit.CodeHandlerExecutionError, resp.Error.Code)
+	assert.Equal(t, 2, resp.Attempts)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestRetry_DefaultRetriesOnlyRetryableErrors(t *testing.T) {
+	// Plain handler errors are not retryable
+	var calls atomic.Int32
+	child := toolkit.NewChild("fetch", "desc_fetch", flakyHandler(&calls, 2), toolkit.WithRetry(toolkit.RetryPolicy{}))
+	resp := runRetryToolkit(t, createTestParent(t, "net", child))
+	assert.Equal(t, toolkit.StatusError, resp.Status)
+	assert.Equal(t, 1, resp.Attempts)
+
+	// Timeouts are
+	calls.Store(0)
+	slowOnce := func(ctx context.Context, args testArgs) (interface{}, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return testResp{Res: "fetched:" + args.Val}, nil
+	}
+	child = toolkit.NewChild("fetch", "desc_fetch", slowOnce,
+		toolkit.WithChildTimeout(20*time.Millisecond),
+		toolkit.WithRetry(toolkit.RetryPolicy{InitialBackoff: time.Millisecond}))
+	resp = runRetryToolkit(t, createTestParent(t, "net", child))
+	assert.Equal(t, toolkit.StatusOK, resp.Status)
+	assert.Equal(t, 2, resp.Attempts)
+}
+
+func TestRetry_RequestChildTimeoutAppliesPerAttempt(t *testing.T) {
+	var calls atomic.Int32
+	slowOnce := func(ctx context.Context, args testArgs) (interface{}, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		time.Sleep(10 * time.Millisecond)
+		return testResp{Res: "fetched:" + args.Val}, nil
+	}
+	retryTimeouts := toolkit.RetryPolicy{InitialBackoff: 10 * time.Millisecond}
+	children := map[string]toolkit.Child{
+		"WithRetry":       toolkit.NewChild("fetch", "desc_fetch", slowOnce, toolkit.WithRetry(retryTimeouts)),
+		"RetryMiddleware": toolkit.WrapChild(toolkit.NewChild("fetch", "desc_fetch", slowOnce), toolkit.RetryMiddleware(retryTimeouts)),
+	}
+	for name, child := range children {
+		t.Run(name, func(t *testing.T) {
+			calls.Store(0)
+			tk := toolkit.New("retry_tk", createTestParent(t, "net", child))
+			// The first attempt and the backoff use up the timeout; the second attempt gets its own
+			ctx := toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{ChildTimeout: 30 * time.Millisecond})
+			input := `{"name":"retry_tk","parents":[{"name":"net","childs":[{"name":"fetch","args":{"val":"a"}}]}]}`
+			resp, err := tk.HandleToolKit(ctx, json.RawMessage(input))
+			require.NoError(t, err)
+
+			childResp := resp.Responses[0].ChildsResponses[0]
+			assert.Equal(t, toolkit.StatusOK, childResp.Status)
+			assert.Equal(t, 2, childResp.Attempts)
+		})
+	}
+}
+
+func TestRetry_StopsWhenContextIsCanceled(t *testing.T) {
+	var calls atomic.Int32
+	policy := fastRetry
+	policy.InitialBackoff = time.Hour
+	child := toolkit.NewChild("fetch", "desc_fetch", flakyHandler(&calls, 5), toolkit.WithRetry(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := child.Handle(ctx, json.RawMessage(`{"val":"a"}`))
+
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, toolkit.CodeHandlerExecutionError, tkErr.Code, "The error of the last attempt should be returned")
+	assert.EqualValues(t, 1, calls.Load())
+	assert.Less(t, time.Since(start), time.Second, "Backoff should end with the context")
+}
+
+func TestRetryMiddleware_RetriesAnyChild(t *testing.T) {
+	var calls atomic.Int32
+	child := toolkit.NewChild("fetch", "desc_fetch", flakyHandler(&calls, 2))
+	parent := toolkit.NewParentWithOptions("net", "desc_net",
+		[]toolkit.ParentOption{toolkit.WithParentMiddleware(toolkit.RetryMiddleware(fastRetry))}, child)
+
+	resp := runRetryToolkit(t, parent)
+	assert.Equal(t, toolkit.StatusOK, resp.Status)
+	assert.Equal(t, 2, resp.Attempts)
+
+	raw, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"attempts":2`)
+}
@@ -0,0 +1,460 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Test Helpers ---
+
+type optionalArgs struct {
+	Path  string `json:"path" jsonschema:"required,description=The path"`
+	Mode  string `json:"mode,omitempty" jsonschema:"enum=fast,enum=slow"`
+	Limit int    `json:"limit,omitempty"`
+	Inner struct {
+		Flag bool `json:"flag,omitempty"`
+	} `json:"inner,omitempty"`
+}
+
+func createSchemaTestToolkit(t *testing.T) *toolkit.Toolkit {
+	t.Helper()
+	optionalChild := toolkit.NewChild("optional", "desc_optional", func(ctx context.Context, args optionalArgs) (interface{}, error) {
+		return testResp{Res: args.Path + ":" + args.Mode}, nil
+	})
+	return toolkit.New("schema_tk",
+		createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), optionalChild),
+		createTestParent(t, "p2", createTestChildFn(t, "c2a", "r2a", false)),
+	)
+}
+
+// toMap converts any JSON-serializable value into its generic map representation.
+func toMap(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &m))
+	return m
+}
+
+// generateOpenAITool returns the OpenAI tool definition of a toolkit that supports strict mode.
+func generateOpenAITool(t *testing.T, tk *toolkit.Toolkit) toolkit.OpenAITool {
+	t.Helper()
+	tool, err := tk.GenerateOpenAITool()
+	require.NoError(t, err)
+	return tool
+}
+
+// walkSchemas calls fn for every schema object nested in node.
+func walkSchemas(node interface{}, path string, fn func(path string, schema map[string]interface{})) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+	fn(path, schema)
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, prop := range props {
+			walkSchemas(prop, path+"/properties/"+name, fn)
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		walkSchemas(items, path+"/items", fn)
+	}
+	for _, keyword := range []string{"anyOf", "oneOf", "allOf"} {
+		if subs, ok := schema[keyword].([]interface{}); ok {
+			for _, sub := range subs {
+				walkSchemas(sub, path+"/"+keyword, fn)
+			}
+		}
+	}
+}
+
+// --- Test OpenAI Provider ---
+
+func TestGetToolkitSchema_OpenAI_Shape(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	schema, err := tk.GetToolkitSchema("openai")
+	require.NoError(t, err)
+	tool, ok := schema.(toolkit.OpenAITool)
+	require.True(t, ok, "OpenAI schema should be a toolkit.OpenAITool")
+
+	m := toMap(t, tool)
+	assert.Equal(t, "function", m["type"])
+	fn := m["function"].(map[string]interface{})
+	assert.Equal(t, "schema_tk", fn["name"])
+	assert.Equal(t, true, fn["strict"])
+	assert.NotEmpty(t, fn["description"])
+	assert.Contains(t, fn, "parameters")
+}
+
+func TestGetToolkitSchema_OpenAI_StrictCompliant(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+	params := toMap(t, generateOpenAITool(t, tk))["function"].(map[string]interface{})["parameters"]
+
+	objects := 0
+	walkSchemas(params, "", func(path string, schema map[string]interface{}) {
+		for _, forbidden := range []string{"oneOf", "allOf", "$schema", "$id", "if", "then"} {
+			assert.NotContains(t, schema, forbidden, "Strict schema at %q must not use %s", path, forbidden)
+		}
+		props, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		objects++
+		assert.Equal(t, false, schema["additionalProperties"], "Object at %q must set additionalProperties:false", path)
+
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		required := make([]string, 0)
+		for _, r := range schema["required"].([]interface{}) {
+			required = append(required, r.(string))
+		}
+		sort.Strings(required)
+		assert.Equal(t, names, required, "Object at %q must list every property as required", path)
+	})
+	assert.Greater(t, objects, 5, "Expected the walk to visit parent, child and args objects")
+}
+
+func TestGetToolkitSchema_OpenAI_OptionalPropertiesAreNullable(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+	params := toMap(t, generateOpenAITool(t, tk))["function"].(map[string]interface{})["parameters"]
+
+	var args map[string]interface{}
+	walkSchemas(params, "", func(path string, schema map[string]interface{}) {
+		props, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		if name, ok := props["name"].(map[string]interface{}); ok {
+			if enum, ok := name["enum"].([]interface{}); ok && len(enum) == 1 && enum[0] == "optional" {
+				args = props["args"].(map[string]interface{})
+			}
+		}
+	})
+	require.NotNil(t, args, "Expected to find the args schema of the 'optional' child")
+
+	argProps := args["properties"].(map[string]interface{})
+	assert.Equal(t, "string", argValueSchema(t, argProps, "path")["type"], "Required properties stay non-nullable")
+	assert.Equal(t, []interface{}{"string", "null"}, argValueSchema(t, argProps, "mode")["type"])
+	assert.Equal(t, []interface{}{"fast", "slow", nil}, argValueSchema(t, argProps, "mode")["enum"])
+	assert.Equal(t, []interface{}{"integer", "null"}, argValueSchema(t, argProps, "limit")["type"])
+}
+
+// argValueSchema returns the schema of an argument value, without its reference alternative.
+func argValueSchema(t *testing.T, argProps map[string]interface{}, name string) map[string]interface{} {
+	t.Helper()
+	alternatives := argProps[name].(map[string]interface{})["anyOf"].([]interface{})
+	require.Len(t, alternatives, 2, "Argument %q should accept a value or a reference", name)
+	return alternatives[0].(map[string]interface{})
+}
+
+// assertRefAlternative checks that the argument accepts a {"$ref": string} reference object.
+func assertRefAlternative(t *testing.T, argProps map[string]interface{}, name string) {
+	t.Helper()
+	alternatives := argProps[name].(map[string]interface{})["anyOf"].([]interface{})
+	require.Len(t, alternatives, 2, "Argument %q should accept a value or a reference", name)
+	ref := alternatives[1].(map[string]interface{})
+	assert.Equal(t, "object", ref["type"])
+	assert.Equal(t, []interface{}{"$ref"}, ref["required"])
+	assert.Equal(t, false, ref["additionalProperties"])
+	assert.Equal(t, "string", ref["properties"].(map[string]interface{})["$ref"].(map[string]interface{})["type"])
+}
+
+func TestGetToolkitSchema_ArgumentsAcceptReferences(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	// Child variants name the child with const (generic schema) or a single-value enum (OpenAI)
+	findArgs := func(schema interface{}) map[string]interface{} {
+		var args map[string]interface{}
+		walkSchemas(schema, "", func(path string, s map[string]interface{}) {
+			props, ok := s["properties"].(map[string]interface{})
+			if !ok {
+				return
+			}
+			name, ok := props["name"].(map[string]interface{})
+			if !ok {
+				return
+			}
+			enum, _ := name["enum"].([]interface{})
+			if name["const"] == "optional" || (len(enum) == 1 && enum[0] == "optional") {
+				args = props["args"].(map[string]interface{})
+			}
+		})
+		require.NotNil(t, args, "Expected to find the args schema of the 'optional' child")
+		return args["properties"].(map[string]interface{})
+	}
+
+	generic := findArgs(toMap(t, tk.GenerateToolkitSchema()))
+	openai := findArgs(toMap(t, generateOpenAITool(t, tk))["function"].(map[string]interface{})["parameters"])
+	for _, name := range []string{"path", "mode", "limit", "inner"} {
+		assertRefAlternative(t, generic, name)
+		assertRefAlternative(t, openai, name)
+	}
+	assert.Equal(t, "string", argValueSchema(t, generic, "path")["type"])
+
+	// The child input schemas are shared, so generating again must not wrap them twice
+	again := findArgs(toMap(t, tk.GenerateToolkitSchema()))
+	assert.Equal(t, generic, again)
+}
+
+type mapArgs struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func TestGetToolkitSchema_OpenAI_RejectsMapArguments(t *testing.T) {
+	labels := toolkit.NewChild("labels", "desc_labels", func(ctx context.Context, args mapArgs) (interface{}, error) {
+		return testResp{Res: "ok"}, nil
+	})
+	tk := toolkit.New("schema_tk", createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), labels))
+
+	_, err := tk.GetToolkitSchema("openai")
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, toolkit.CodeUnsupportedSchema, tkErr.Code)
+	assert.Contains(t, tkErr.Message, "'labels'")
+	assert.Contains(t, tkErr.Message, "/properties/labels/additionalProperties")
+
+	// Other providers are not affected
+	_, err = tk.GetToolkitSchema("anthropic")
+	assert.NoError(t, err)
+}
+
+func TestParseOpenAIArguments_DropsNullsAndExecutes(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	arguments := `{"name":"schema_tk","parents":[{"name":"p1","childs":[
+		{"name":"optional","args":{"path":"a.txt","mode":null,"limit":null,"inner":null}}
+	]}]}`
+
+	input, err := toolkit.ParseOpenAIArguments(arguments)
+	require.NoError(t, err)
+	assert.NotContains(t, string(input), "null")
+
+	resp, err := tk.HandleToolKit(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 1)
+	assert.Equal(t, testResp{Res: "a.txt:"}, resp.Responses[0].ChildsResponses[0].Result)
+
+	_, err = toolkit.ParseOpenAIArguments(`{"broken`)
+	assert.Error(t, err)
+}
+
+// --- Test Schema Provider Registry ---
+
+func TestRegisterSchemaProvider_CustomProvider(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	toolkit.RegisterSchemaProvider(toolkit.NewSchemaProvider("test_gateway", func(tk *toolkit.Toolkit) (interface{}, error) {
+		names := []string{}
+		for _, parent := range tk.GetParents() {
+			for name := range parent.GetChildren() {
+				names = append(names, parent.GetName()+"."+name)
+			}
+		}
+		sort.Strings(names)
+		return map[string]interface{}{"tool": tk.GetToolkitName(), "children": names}, nil
+	}))
+	assert.Contains(t, toolkit.SchemaProviders(), "test_gateway")
+
+	schema, err := tk.GetToolkitSchema("test_gateway")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tool":     "schema_tk",
+		"children": []string{"p1.c1a", "p1.optional", "p2.c2a"},
+	}, schema)
+}
+
+func TestRegisterSchemaProvider_ProviderError(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	toolkit.RegisterSchemaProvider(toolkit.NewSchemaProvider("test_failing", func(*toolkit.Toolkit) (interface{}, error) {
+		return nil, errors.New("gateway unavailable")
+	}))
+
+	schema, err := tk.GetToolkitSchema("test_failing")
+	assert.Nil(t, schema)
+	assert.EqualError(t, err, "gateway unavailable")
+}
+
+func TestRegisterSchemaProvider_InvalidProvider(t *testing.T) {
+	assert.Panics(t, func() { toolkit.RegisterSchemaProvider(nil) })
+	assert.Panics(t, func() {
+		toolkit.RegisterSchemaProvider(toolkit.NewSchemaProvider("", func(*toolkit.Toolkit) (interface{}, error) { return nil, nil }))
+	})
+}
+
+func TestSchemaProviders_BuiltIns(t *testing.T) {
+	providers := toolkit.SchemaProviders()
+	for _, name := range []string{"anthropic", "openai", "gemini"} {
+		assert.Contains(t, providers, name)
+	}
+	assert.True(t, sort.StringsAreSorted(providers), "Provider names should be sorted")
+}
+
+// --- Test Gemini Provider ---
+
+func TestGetToolkitSchema_Gemini_Shape(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	schema, err := tk.GetToolkitSchema("gemini")
+	require.NoError(t, err)
+	decl, ok := schema.(toolkit.GeminiFunctionDeclaration)
+	require.True(t, ok, "Gemini schema should be a toolkit.GeminiFunctionDeclaration")
+
+	m := toMap(t, decl)
+	assert.Equal(t, "schema_tk", m["name"])
+	assert.NotEmpty(t, m["description"])
+
+	params := m["parameters"].(map[string]interface{})
+	assert.Equal(t, "OBJECT", params["type"])
+	parents := params["properties"].(map[string]interface{})
+	require.Contains(t, parents, "p1")
+	require.Contains(t, parents, "p2")
+
+	children := parents["p1"].(map[string]interface{})["properties"].(map[string]interface{})
+	optional := children["optional"].(map[string]interface{})
+	assert.Equal(t, "ARRAY", optional["type"])
+	assert.Equal(t, "desc_optional", optional["description"])
+
+	args := optional["items"].(map[string]interface{})
+	assert.Equal(t, "OBJECT", args["type"])
+	assert.Equal(t, []interface{}{"path"}, args["required"])
+	argProps := args["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "STRING", "description": "The path"}, argProps["path"])
+	assert.Equal(t, map[string]interface{}{"type": "STRING", "format": "enum", "enum": []interface{}{"fast", "slow"}}, argProps["mode"])
+	assert.Equal(t, "INTEGER", argProps["limit"].(map[string]interface{})["type"])
+}
+
+func TestGetToolkitSchema_Gemini_UsesSupportedSubset(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+	params := toMap(t, tk.GenerateGeminiFunctionDeclaration())["parameters"]
+
+	allowedTypes := map[interface{}]bool{"STRING": true, "NUMBER": true, "INTEGER": true, "BOOLEAN": true, "ARRAY": true, "OBJECT": true}
+	walkSchemas(params, "", func(path string, schema map[string]interface{}) {
+		for _, forbidden := range []string{"$schema", "$id", "$defs", "additionalProperties", "oneOf", "const"} {
+			assert.NotContains(t, schema, forbidden, "Gemini schema at %q must not use %s", path, forbidden)
+		}
+		if typ, ok := schema["type"]; ok {
+			assert.True(t, allowedTypes[typ], "Unsupported type %v at %q", typ, path)
+		}
+	})
+}
+
+type noArgs struct{}
+
+type geminiEdgeArgs struct {
+	Path   string            `json:"path" jsonschema:"required"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Any    []interface{}     `json:"any,omitempty"`
+	Value  interface{}       `json:"value,omitempty"`
+}
+
+type requiredMapArgs struct {
+	Labels map[string]string `json:"labels" jsonschema:"required"`
+}
+
+func TestGetToolkitSchema_Gemini_UnsupportedShapes(t *testing.T) {
+	ping := toolkit.NewChild("ping", "desc_ping", func(ctx context.Context, args noArgs) (interface{}, error) {
+		return testResp{Res: "pong"}, nil
+	})
+	edge := toolkit.NewChild("edge", "desc_edge", func(ctx context.Context, args geminiEdgeArgs) (interface{}, error) {
+		return testResp{Res: args.Path}, nil
+	})
+	labels := toolkit.NewChild("labels", "desc_labels", func(ctx context.Context, args requiredMapArgs) (interface{}, error) {
+		return testResp{Res: "ok"}, nil
+	})
+	tk := toolkit.New("gemini_tk",
+		createTestParent(t, "p1", ping, edge, labels),
+		createTestParent(t, "p2", labels),
+	)
+	params := toMap(t, tk.GenerateGeminiFunctionDeclaration())["parameters"].(map[string]interface{})
+
+	walkSchemas(params, "", func(path string, schema map[string]interface{}) {
+		assert.Contains(t, schema, "type", "Gemini schema at %q must have a type", path)
+		if schema["type"] == "OBJECT" {
+			assert.NotEmpty(t, schema["properties"], "Gemini object at %q must have properties", path)
+		}
+		if schema["type"] == "ARRAY" {
+			assert.Contains(t, schema, "items", "Gemini array at %q must have items", path)
+		}
+	})
+
+	parents := params["properties"].(map[string]interface{})
+	assert.NotContains(t, parents, "p2", "Parents without expressible children should be omitted")
+	children := parents["p1"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.NotContains(t, children, "labels", "Children with a required map argument should be omitted")
+	assert.Equal(t, "BOOLEAN", children["ping"].(map[string]interface{})["type"], "Children without arguments should be a boolean")
+
+	edgeArgs := children["edge"].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"path"}, edgeArgs["required"])
+	assert.Equal(t, []string{"path"}, keys(edgeArgs["properties"].(map[string]interface{})),
+		"Maps, arrays without item schema and untyped values should be left out")
+
+	// A true boolean runs a child without arguments once
+	input, err := tk.ParseGeminiFunctionCall("gemini_tk", `{"p1": {"ping": true, "edge": [{"path": "a"}]}}`)
+	require.NoError(t, err)
+	resp, err := tk.HandleToolKit(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"ok", "ok"}}, childCodes(t, resp))
+
+	input, err = tk.ParseGeminiFunctionCall("gemini_tk", `{"p1": {"ping": false}}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"gemini_tk","parents":[{"name":"p1","childs":[]}]}`, string(input))
+}
+
+func TestParseGeminiFunctionCall(t *testing.T) {
+	tk := createSchemaTestToolkit(t)
+
+	// Raw JSON keeps the order chosen by the model; nulls are dropped
+	args := json.RawMessage(`{
+		"p2": {"c2a": [{}]},
+		"p1": {"optional": [{"path":"a.txt","mode":"fast"}, {"path":"b.txt","mode":null}], "c1a": {}}
+	}`)
+	input, err := tk.ParseGeminiFunctionCall("schema_tk", args)
+	require.NoError(t, err)
+
+	var request toolkit.ToolKit
+	require.NoError(t, json.Unmarshal(input, &request))
+	assert.Equal(t, "schema_tk", request.Name)
+	require.Len(t, request.ToolKitParents, 2)
+	assert.Equal(t, "p2", request.ToolKitParents[0].Name)
+	assert.Equal(t, "p1", request.ToolKitParents[1].Name)
+	childs := request.ToolKitParents[1].ToolKitChilds
+	require.Len(t, childs, 3)
+	assert.Equal(t, "optional", childs[0].Name)
+	assert.JSONEq(t, `{"path":"b.txt"}`, string(childs[1].Args))
+	assert.Equal(t, "c1a", childs[2].Name, "A single arguments object is one invocation")
+
+	resp, err := tk.HandleToolKit(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	p1 := resp.Responses[1].ChildsResponses
+	assert.Equal(t, testResp{Res: "a.txt:fast"}, p1[0].Result)
+	assert.Equal(t, testResp{Res: "b.txt:"}, p1[1].Result)
+
+	// Decoded SDK maps are processed in name order
+	input, err = tk.ParseGeminiFunctionCall("schema_tk", map[string]interface{}{
+		"p2": map[string]interface{}{"c2a": []interface{}{map[string]interface{}{}}},
+		"p1": map[string]interface{}{"c1a": []interface{}{map[string]interface{}{}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(input, &request))
+	assert.Equal(t, "p1", request.ToolKitParents[0].Name)
+
+	_, err = tk.ParseGeminiFunctionCall("other_tk", args)
+	assert.Error(t, err, "Calls for another function should be rejected")
+	_, err = tk.ParseGeminiFunctionCall("schema_tk", `{"p1": {"c1a": ["oops"]}}`)
+	assert.Error(t, err, "Non-object arguments should be rejected")
+}
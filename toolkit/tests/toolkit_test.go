@@ -3,9 +3,13 @@ package tests
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/h-ess/ai-toolkit/toolkit"
 
@@ -40,6 +44,31 @@ func createTestChildFn(t *testing.T, name string, retVal string, shouldErr bool)
 	return toolkit.NewChild[testArgs](name, "desc_"+name, handler)
 }
 
+// errorCode returns the ToolKitError code of err, or "ok" if err is nil.
+func errorCode(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return "ok"
+	}
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	return tkErr.Code
+}
+
+// childCodes returns the errorCode of every child response, grouped by parent.
+func childCodes(t *testing.T, resp toolkit.ToolKitResponse) [][]string {
+	t.Helper()
+	codes := make([][]string, 0, len(resp.Responses))
+	for _, parentResp := range resp.Responses {
+		var parentCodes []string
+		for _, childResp := range parentResp.ChildsResponses {
+			parentCodes = append(parentCodes, errorCode(t, childResp.Err()))
+		}
+		codes = append(codes, parentCodes)
+	}
+	return codes
+}
+
 // --- Test New ---
 
 func TestNew(t *testing.T) {
@@ -161,10 +190,10 @@ func TestHandleToolKit_Success(t *testing.T) {
 	require.Len(t, pr1.ChildsResponses, 2)
 	cr1b := pr1.ChildsResponses[0]
 	assert.Equal(t, "c1b", cr1b.Name)
-	assert.Equal(t, testResp{Res: "r1b:v1b"}, cr1b.Response)
+	assert.Equal(t, testResp{Res: "r1b:v1b"}, cr1b.Result)
 	cr1a := pr1.ChildsResponses[1]
 	assert.Equal(t, "c1a", cr1a.Name)
-	assert.Equal(t, testResp{Res: "r1a:v1a"}, cr1a.Response)
+	assert.Equal(t, testResp{Res: "r1a:v1a"}, cr1a.Result)
 
 	// Check Parent 2 response
 	pr2 := resp.Responses[1]
@@ -172,7 +201,7 @@ func TestHandleToolKit_Success(t *testing.T) {
 	require.Len(t, pr2.ChildsResponses, 1)
 	cr2a := pr2.ChildsResponses[0]
 	assert.Equal(t, "c2a", cr2a.Name)
-	assert.Equal(t, testResp{Res: "r2a:v2a"}, cr2a.Response)
+	assert.Equal(t, testResp{Res: "r2a:v2a"}, cr2a.Result)
 }
 
 func TestHandleToolKit_ParseError(t *testing.T) {
@@ -192,7 +221,7 @@ func TestHandleToolKit_ParseError(t *testing.T) {
 	require.Len(t, pr.ChildsResponses, 1)
 	cr := pr.ChildsResponses[0]
 	assert.Equal(t, "_input_error", cr.Name)
-	tkErr, ok := cr.Response.(toolkit.ToolKitError)
+	tkErr, ok := cr.Err().(toolkit.ToolKitError)
 	require.True(t, ok, "Expected response to be ToolKitError")
 	assert.Equal(t, "invalid_input_json", tkErr.Code)
 }
@@ -220,7 +249,7 @@ func TestHandleToolKit_ParentNotFound(t *testing.T) {
 	require.Len(t, pr.ChildsResponses, 1)
 	cr := pr.ChildsResponses[0]
 	assert.Equal(t, "_parent_error", cr.Name)
-	tkErr, ok := cr.Response.(toolkit.ToolKitError)
+	tkErr, ok := cr.Err().(toolkit.ToolKitError)
 	require.True(t, ok, "Expected response to be ToolKitError")
 	assert.Equal(t, "parent_not_found", tkErr.Code)
 }
@@ -248,7 +277,7 @@ func TestHandleToolKit_ChildNotFound(t *testing.T) {
 	require.Len(t, pr.ChildsResponses, 1)
 	cr := pr.ChildsResponses[0]
 	assert.Equal(t, "non_existent_child", cr.Name)
-	tkErr, ok := cr.Response.(toolkit.ToolKitError)
+	tkErr, ok := cr.Err().(toolkit.ToolKitError)
 	require.True(t, ok, "Expected response to be ToolKitError")
 	assert.Equal(t, "child_not_found", tkErr.Code)
 }
@@ -275,7 +304,7 @@ func TestHandleToolKit_ChildHandlerError(t *testing.T) {
 	require.Len(t, pr.ChildsResponses, 1)
 	cr := pr.ChildsResponses[0]
 	assert.Equal(t, "c1a_err", cr.Name)
-	tkErr, ok := cr.Response.(toolkit.ToolKitError)
+	tkErr, ok := cr.Err().(toolkit.ToolKitError)
 	require.True(t, ok, "Expected response to be ToolKitError")
 	assert.Equal(t, "handler_execution_error", tkErr.Code)
 }
@@ -303,11 +332,233 @@ func TestHandleToolKit_ChildUnmarshalError(t *testing.T) {
 	require.Len(t, pr.ChildsResponses, 1)
 	cr := pr.ChildsResponses[0]
 	assert.Equal(t, "c1a", cr.Name)
-	tkErr, ok := cr.Response.(toolkit.ToolKitError)
+	tkErr, ok := cr.Err().(toolkit.ToolKitError)
 	require.True(t, ok, "Expected response to be ToolKitError")
 	assert.Equal(t, "invalid_arguments", tkErr.Code)
 }
 
+// --- Test Concurrent Execution ---
+
+// createSlowChild returns a child that sleeps for the given delay while tracking
+// how many instances run at the same time through the shared counters.
+func createSlowChild(t *testing.T, name string, delay time.Duration, running, maxRunning *int32) toolkit.Child {
+	t.Helper()
+	handler := func(ctx context.Context, args testArgs) (interface{}, error) {
+		n := atomic.AddInt32(running, 1)
+		defer atomic.AddInt32(running, -1)
+		for {
+			m := atomic.LoadInt32(maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		return testResp{Res: name + ":" + args.Val}, nil
+	}
+	return toolkit.NewChild[testArgs](name, "desc_"+name, handler)
+}
+
+func TestHandleToolKit_Concurrent_PreservesOrder(t *testing.T) {
+	var running, maxRunning int32
+	parent1 := createTestParent(t, "parent1",
+		createSlowChild(t, "slow", 60*time.Millisecond, &running, &maxRunning),
+		createSlowChild(t, "fast", time.Millisecond, &running, &maxRunning),
+	)
+	parent2 := createTestParent(t, "parent2",
+		createSlowChild(t, "medium", 30*time.Millisecond, &running, &maxRunning),
+	)
+	tk := toolkit.NewWithOptions("test_concurrent",
+		[]toolkit.Option{toolkit.WithExecutionMode(toolkit.ExecutionConcurrent)},
+		parent1, parent2,
+	)
+
+	inputJSON := `{
+		"name": "toolkit",
+		"parents": [
+			{"name": "parent1", "childs": [
+				{"name": "slow", "args": {"val": "a"}},
+				{"name": "fast", "args": {"val": "b"}},
+				{"name": "missing", "args": {}}
+			]},
+			{"name": "unknown_parent", "childs": []},
+			{"name": "parent2", "childs": [{"name": "medium", "args": {"val": "c"}}]}
+		]
+	}`
+
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 3)
+
+	pr1 := resp.Responses[0]
+	assert.Equal(t, "parent1", pr1.Name)
+	require.Len(t, pr1.ChildsResponses, 3)
+	assert.Equal(t, "slow", pr1.ChildsResponses[0].Name)
+	assert.Equal(t, testResp{Res: "slow:a"}, pr1.ChildsResponses[0].Result)
+	assert.Equal(t, "fast", pr1.ChildsResponses[1].Name)
+	assert.Equal(t, testResp{Res: "fast:b"}, pr1.ChildsResponses[1].Result)
+	assert.Equal(t, "missing", pr1.ChildsResponses[2].Name)
+	tkErr, ok := pr1.ChildsResponses[2].Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected response to be ToolKitError")
+	assert.Equal(t, "child_not_found", tkErr.Code)
+
+	assert.Equal(t, "unknown_parent", resp.Responses[1].Name)
+	assert.Equal(t, "parent2", resp.Responses[2].Name)
+	assert.Equal(t, testResp{Res: "medium:c"}, resp.Responses[2].ChildsResponses[0].Result)
+
+	assert.Greater(t, atomic.LoadInt32(&maxRunning), int32(1), "Children should have overlapped in concurrent mode")
+}
+
+func TestHandleToolKit_Concurrent_RespectsLimit(t *testing.T) {
+	var running, maxRunning int32
+	children := make([]toolkit.Child, 0, 6)
+	childReqs := make([]string, 0, 6)
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("c%d", i)
+		children = append(children, createSlowChild(t, name, 20*time.Millisecond, &running, &maxRunning))
+		childReqs = append(childReqs, fmt.Sprintf(`{"name": "%s", "args": {"val": "v"}}`, name))
+	}
+	tk := toolkit.NewWithOptions("test_limit",
+		[]toolkit.Option{
+			toolkit.WithExecutionMode(toolkit.ExecutionConcurrent),
+			toolkit.WithMaxConcurrency(2),
+		},
+		createTestParent(t, "p1", children[:3]...),
+		createTestParent(t, "p2", children[3:]...),
+	)
+
+	inputJSON := fmt.Sprintf(`{"name": "toolkit", "parents": [
+		{"name": "p1", "childs": [%s]},
+		{"name": "p2", "childs": [%s]}
+	]}`, strings.Join(childReqs[:3], ","), strings.Join(childReqs[3:], ","))
+
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	for _, pr := range resp.Responses {
+		require.Len(t, pr.ChildsResponses, 3)
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(2), "No more than 2 children should run at once")
+}
+
+func TestHandleToolKit_Concurrent_AbandonedHandlersKeepTheirSlot(t *testing.T) {
+	var running, maxRunning int32
+	parent := createTestParent(t, "p1",
+		createSlowChild(t, "a", 60*time.Millisecond, &running, &maxRunning),
+		createSlowChild(t, "b", 60*time.Millisecond, &running, &maxRunning),
+		createSlowChild(t, "c", 60*time.Millisecond, &running, &maxRunning),
+	)
+	tk := toolkit.NewWithOptions("test_limit",
+		[]toolkit.Option{
+			toolkit.WithExecutionMode(toolkit.ExecutionConcurrent),
+			toolkit.WithMaxConcurrency(1),
+		},
+		parent,
+	)
+
+	// The handlers ignore their context, so they keep running after their timeout
+	ctx := toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{ChildTimeout: 10 * time.Millisecond})
+	inputJSON := `{"name": "toolkit", "parents": [{"name": "p1", "childs": [
+		{"name": "a", "args": {"val": "1"}}, {"name": "b", "args": {"val": "2"}}, {"name": "c", "args": {"val": "3"}}
+	]}]}`
+	resp, err := tk.HandleToolKit(ctx, json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"timeout", "timeout", "timeout"}}, childCodes(t, resp))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning), "Abandoned handlers should keep their slot until they return")
+}
+
+func TestHandleToolKit_Sequential_IsDefault(t *testing.T) {
+	var running, maxRunning int32
+	parent := createTestParent(t, "p1",
+		createSlowChild(t, "a", 5*time.Millisecond, &running, &maxRunning),
+		createSlowChild(t, "b", 5*time.Millisecond, &running, &maxRunning),
+	)
+	tk := toolkit.New("test_sequential", parent)
+
+	inputJSON := `{"name": "toolkit", "parents": [
+		{"name": "p1", "childs": [{"name": "a", "args": {"val": "1"}}, {"name": "b", "args": {"val": "2"}}]},
+		{"name": "p1", "childs": [{"name": "a", "args": {"val": "3"}}]}
+	]}`
+
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning), "Children should never overlap in sequential mode")
+}
+
+func TestHandleToolKit_Timeout_KeepsFinishedResults(t *testing.T) {
+	var running, maxRunning int32
+	parent := createTestParent(t, "p1",
+		createSlowChild(t, "fast", time.Millisecond, &running, &maxRunning),
+		createSlowChild(t, "slow", time.Second, &running, &maxRunning),
+	)
+	tk := toolkit.NewWithOptions("test_timeout",
+		[]toolkit.Option{
+			toolkit.WithExecutionMode(toolkit.ExecutionConcurrent),
+			toolkit.WithTimeout(50 * time.Millisecond),
+		},
+		parent,
+	)
+
+	inputJSON := `{"name": "toolkit", "parents": [
+		{"name": "p1", "childs": [{"name": "slow", "args": {"val": "1"}}, {"name": "fast", "args": {"val": "2"}}]}
+	]}`
+
+	start := time.Now()
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "The toolkit timeout should bound the request")
+
+	require.Len(t, resp.Responses, 1)
+	crs := resp.Responses[0].ChildsResponses
+	require.Len(t, crs, 2)
+	tkErr, ok := crs[0].Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected response to be ToolKitError")
+	assert.Equal(t, "timeout", tkErr.Code)
+	assert.Equal(t, testResp{Res: "fast:2"}, crs[1].Result)
+}
+
+func TestHandleToolKit_RequestTimeoutOverride(t *testing.T) {
+	var running, maxRunning int32
+	parent := createTestParent(t, "p1", createSlowChild(t, "slow", 200*time.Millisecond, &running, &maxRunning))
+	tk := toolkit.New("test_timeout_override", parent)
+
+	inputJSON := `{"name": "toolkit", "parents": [{"name": "p1", "childs": [{"name": "slow", "args": {"val": "1"}}]}]}`
+
+	ctx := toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{Timeout: 20 * time.Millisecond})
+	resp, err := tk.HandleToolKit(ctx, json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	tkErr, ok := resp.Responses[0].ChildsResponses[0].Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected response to be ToolKitError")
+	assert.Equal(t, "timeout", tkErr.Code)
+}
+
+func TestHandleToolKit_Timeout_OneGoroutinePerCall(t *testing.T) {
+	// The handler goroutine should be started by the goroutine running the request
+	var creator string
+	child := toolkit.NewChild("stack", "desc_stack", func(ctx context.Context, args testArgs) (interface{}, error) {
+		own := make([]byte, 1<<16)
+		own = own[:runtime.Stack(own, false)]
+		_, createdIn, _ := strings.Cut(string(own), "created by ")
+		_, id, _ := strings.Cut(createdIn, " in goroutine ")
+		id, _, _ = strings.Cut(id, "\n")
+
+		all := make([]byte, 1<<20)
+		for _, goroutine := range strings.Split(string(all[:runtime.Stack(all, true)]), "\n\n") {
+			if strings.HasPrefix(goroutine, "goroutine "+id+" ") {
+				creator = goroutine
+			}
+		}
+		return testResp{Res: "ok"}, nil
+	}, toolkit.WithChildTimeout(time.Second))
+	tk := toolkit.NewWithOptions("test_timeout", []toolkit.Option{toolkit.WithTimeout(time.Second)}, createTestParent(t, "p1", child))
+
+	inputJSON := `{"name": "toolkit", "parents": [{"name": "p1", "childs": [{"name": "stack", "args": {"val": "1"}}]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(inputJSON))
+	require.NoError(t, err)
+	assert.Equal(t, toolkit.StatusOK, resp.Responses[0].ChildsResponses[0].Status)
+	assert.Contains(t, creator, "TestHandleToolKit_Timeout_OneGoroutinePerCall", "The handler should not run behind a second goroutine")
+}
+
 // --- Test GetToolkitDescription ---
 
 func TestGetToolkitDescription(t *testing.T) {
@@ -335,6 +586,7 @@ func TestGetToolkitDescription(t *testing.T) {
 				`<toolkit name="tk_empty">`,
 				`</toolkit>`,
 				`Below is the list of available <parents> and their <childs>:`,
+				`{"$ref": "<parent>.<child>#<n>/<json pointer>"}`,
 			},
 		},
 		{
@@ -373,6 +625,73 @@ func TestGetToolkitDescription(t *testing.T) {
 	}
 }
 
+func TestGetToolkitDescription_Deterministic(t *testing.T) {
+	tk := toolkit.New("tk_order",
+		createTestParent(t, "zeta", createTestChildFn(t, "z2", "", false), createTestChildFn(t, "z1", "", false)),
+		createTestParent(t, "alpha", createTestChildFn(t, "a1", "", false)),
+		createTestParent(t, "mid", createTestChildFn(t, "m2", "", false), createTestChildFn(t, "m3", "", false), createTestChildFn(t, "m1", "", false)),
+	)
+
+	desc := tk.GetToolkitDescription()
+	for i := 0; i < 20; i++ {
+		require.Equal(t, desc, tk.GetToolkitDescription(), "Description should not change between calls")
+	}
+
+	// Parents and children are listed in name order
+	order := []string{`"alpha"`, `"a1"`, `"mid"`, `"m1"`, `"m2"`, `"m3"`, `"zeta"`, `"z1"`, `"z2"`}
+	last := -1
+	for _, name := range order {
+		idx := strings.Index(desc, "name="+name)
+		require.Greater(t, idx, last, "%s should follow the previous entry", name)
+		last = idx
+	}
+}
+
+// budgetArgs has nested objects and descriptions that the description budget can shorten.
+type budgetArgs struct {
+	Query   string `json:"query" jsonschema:"required,description=A long description of the search query that takes up a lot of room"`
+	Filters struct {
+		Language string `json:"language" jsonschema:"description=The language of the results as an ISO 639-1 code"`
+		Region   string `json:"region" jsonschema:"description=The region of the results as an ISO 3166-1 code"`
+	} `json:"filters" jsonschema:"description=Filters narrowing the results"`
+}
+
+func TestGetToolkitDescription_Budget(t *testing.T) {
+	newToolkit := func(budget toolkit.DescriptionBudget) *toolkit.Toolkit {
+		search := toolkit.NewTypedChild("search", "desc_search", func(ctx context.Context, args budgetArgs) (testResp, error) {
+			return testResp{}, nil
+		})
+		return toolkit.NewWithOptions("tk_budget", []toolkit.Option{toolkit.WithDescriptionBudget(budget)}, createTestParent(t, "p", search))
+	}
+	full := newToolkit(toolkit.DescriptionBudget{}).GetToolkitDescription()
+	require.Contains(t, full, "ISO 639-1")
+	require.Contains(t, full, `"language"`)
+	require.Contains(t, full, "<output_schema>")
+
+	// Dropping descriptions keeps the structure
+	noDescriptions := newToolkit(toolkit.DescriptionBudget{MaxBytes: len(full) - 1}).GetToolkitDescription()
+	assert.Less(t, len(noDescriptions), len(full))
+	assert.NotContains(t, noDescriptions, "ISO 639-1")
+	assert.NotContains(t, noDescriptions, "$schema")
+	assert.Contains(t, noDescriptions, `"language"`)
+	assert.Contains(t, noDescriptions, `desc_search`, "Child descriptions are never dropped")
+
+	// Nested objects are collapsed next, then output schemas are omitted
+	collapsed := newToolkit(toolkit.DescriptionBudget{MaxBytes: len(noDescriptions) - 1}).GetToolkitDescription()
+	assert.Less(t, len(collapsed), len(noDescriptions))
+	assert.NotContains(t, collapsed, `"language"`)
+	assert.Contains(t, collapsed, `"filters":{"type":"object"}`)
+	assert.Contains(t, collapsed, "<output_schema>")
+
+	minimal := newToolkit(toolkit.DescriptionBudget{MaxTokens: len(collapsed)/4 - 1}).GetToolkitDescription()
+	assert.Less(t, len(minimal), len(collapsed))
+	assert.NotContains(t, minimal, "<output_schema>")
+	assert.Contains(t, minimal, `"query"`)
+
+	// A budget that cannot be met returns the shortest description
+	assert.Equal(t, minimal, newToolkit(toolkit.DescriptionBudget{MaxBytes: 10}).GetToolkitDescription())
+}
+
 // --- Test GetToolkitSchema ---
 
 func TestGetToolkitSchema(t *testing.T) {
@@ -380,7 +699,8 @@ func TestGetToolkitSchema(t *testing.T) {
 	require.NotNil(t, tk)
 
 	// Test known provider
-	anthropicSchema := tk.GetToolkitSchema("anthropic")
+	anthropicSchema, err := tk.GetToolkitSchema("anthropic")
+	require.NoError(t, err)
 	assert.NotNil(t, anthropicSchema, "Schema for known provider 'anthropic' should not be nil")
 
 	// Check the actual type returned by the jsonschema library
@@ -389,10 +709,329 @@ func TestGetToolkitSchema(t *testing.T) {
 	assert.Equal(t, "object", schemaPtr.Type, "Expected schema type to be object")
 	assert.NotNil(t, schemaPtr.Properties, "Expected schema properties to be non-nil")
 
-	// Test unknown provider (should default/log warning - can't test log easily)
-	unknownSchema := tk.GetToolkitSchema("unknown_provider")
-	assert.NotNil(t, unknownSchema, "Schema for unknown provider should default and not be nil")
-	assert.Equal(t, anthropicSchema, unknownSchema, "Schema for unknown provider should default to Anthropic schema")
+	// Test unknown provider
+	unknownSchema, err := tk.GetToolkitSchema("unknown_provider")
+	assert.Nil(t, unknownSchema, "Schema for unknown provider should be nil")
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, err, &tkErr)
+	assert.Equal(t, "unknown_schema_provider", tkErr.Code)
 }
 
-// Removed TODO placeholders
+func TestGetToolkitSchema_EmbedsChildSchemas(t *testing.T) {
+	parent1 := createTestParent(t, "p1",
+		createTestChildFn(t, "c1b", "r1b", false),
+		createTestChildFn(t, "c1a", "r1a", false),
+	)
+	parent2 := createTestParent(t, "p2", createTestChildFn(t, "c2a", "r2a", false))
+	tk := toolkit.New("test_schema_embed", parent2, parent1)
+
+	raw, err := json.Marshal(tk.GenerateToolkitSchema())
+	require.NoError(t, err)
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+
+	props := schema["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"test_schema_embed"}, props["name"].(map[string]interface{})["enum"])
+
+	parentItem := props["parents"].(map[string]interface{})["items"].(map[string]interface{})
+	parentName := parentItem["properties"].(map[string]interface{})["name"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"p1", "p2"}, parentName["enum"], "Parent names should be a sorted enum")
+
+	variants := parentItem["oneOf"].([]interface{})
+	require.Len(t, variants, 2)
+
+	p1Props := variants[0].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, "p1", p1Props["name"].(map[string]interface{})["const"])
+
+	childItem := p1Props["childs"].(map[string]interface{})["items"].(map[string]interface{})
+	childName := childItem["properties"].(map[string]interface{})["name"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"c1a", "c1b"}, childName["enum"], "Child names should be a sorted enum")
+
+	childVariants := childItem["oneOf"].([]interface{})
+	require.Len(t, childVariants, 2)
+	c1aProps := childVariants[0].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, "c1a", c1aProps["name"].(map[string]interface{})["const"])
+
+	args := c1aProps["args"].(map[string]interface{})
+	assert.Equal(t, "object", args["type"])
+	assert.Contains(t, args["properties"], "val", "Child args should embed the child's input schema")
+	assert.NotContains(t, args, "$schema", "Embedded schemas should not carry their own $schema")
+
+	// The generated schema must be stable across calls
+	again, err := json.Marshal(tk.GenerateToolkitSchema())
+	require.NoError(t, err)
+	assert.Equal(t, string(raw), string(again))
+}
+
+// --- Test Response Envelope ---
+
+// plainErrorChild is a custom Child that fails with an error that is not a ToolKitError.
+type plainErrorChild struct{}
+
+func (plainErrorChild) GetName() string             { return "plain" }
+func (plainErrorChild) GetDescription() string      { return "desc_plain" }
+func (plainErrorChild) GetInputSchema() interface{} { return map[string]interface{}{"type": "object"} }
+func (plainErrorChild) Handle(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	return nil, errors.New("disk on fire")
+}
+
+func TestChildResponse_Envelope(t *testing.T) {
+	parent := createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), plainErrorChild{},
+		toolkit.NewChild("slow", "desc_slow", func(ctx context.Context, args testArgs) (interface{}, error) {
+			time.Sleep(200 * time.Millisecond)
+			return testResp{}, nil
+		}))
+	tk := toolkit.NewWithOptions("env_tk", []toolkit.Option{toolkit.WithExecutionPolicy(toolkit.PolicyStopOnFirstError)}, parent)
+
+	input := `{"name":"env_tk","parents":[{"name":"p1","childs":[
+		{"name":"c1a","args":{"val":"x"}},
+		{"name":"plain","args":{}},
+		{"name":"slow","args":{}}
+	]}]}`
+	resp, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	// Success and failure shapes are distinguishable once serialized
+	raw, err := json.Marshal(resp.Responses[0].ChildsResponses)
+	require.NoError(t, err)
+	var childs []map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &childs))
+	require.Len(t, childs, 3)
+	assert.Equal(t, map[string]interface{}{"name": "c1a", "status": "ok", "result": map[string]interface{}{"res": "r1a:x"}}, childs[0])
+	assert.Equal(t, "error", childs[1]["status"])
+	assert.NotContains(t, childs[1], "result")
+	assert.Equal(t, "handler_execution_error", childs[1]["error"].(map[string]interface{})["Code"], "Plain errors should not serialize as {}")
+	assert.Contains(t, childs[1]["error"].(map[string]interface{})["Message"], "disk on fire")
+	assert.Equal(t, "skipped", childs[2]["status"])
+
+	assert.True(t, resp.HasErrors())
+	assert.True(t, resp.Responses[0].HasErrors())
+	failures := resp.Errors()
+	require.Len(t, failures, 2)
+	assert.Equal(t, toolkit.ChildError{Parent: "p1", Child: "plain", ChildIndex: 1, Status: toolkit.StatusError, Err: *resp.Responses[0].ChildsResponses[1].Error}, failures[0])
+	assert.Equal(t, toolkit.StatusSkipped, failures[1].Status)
+	assert.Equal(t, 2, failures[1].ChildIndex)
+	var tkErr toolkit.ToolKitError
+	require.ErrorAs(t, failures[1], &tkErr)
+	assert.Equal(t, "skipped", tkErr.Code)
+	assert.Contains(t, failures[1].Error(), "p1.slow: skipped")
+
+	// Children exceeding their deadline report the timeout status
+	ctx := toolkit.ContextWithRequestOptions(context.Background(), toolkit.RequestOptions{ChildTimeout: 10 * time.Millisecond})
+	resp, err = tk.HandleToolKit(ctx, json.RawMessage(`{"name":"env_tk","parents":[{"name":"p1","childs":[{"name":"slow","args":{}}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, toolkit.StatusTimeout, resp.Responses[0].ChildsResponses[0].Status)
+
+	resp, err = tk.HandleToolKit(context.Background(), json.RawMessage(`{"name":"env_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{}}]}]}`))
+	require.NoError(t, err)
+	assert.False(t, resp.HasErrors())
+	assert.Empty(t, resp.Errors())
+}
+
+// --- Test Flat Mode ---
+
+func TestGetFlatTools(t *testing.T) {
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "c1b", "r1b", false), createTestChildFn(t, "c1a", "r1a", false))
+	parent2 := createTestParent(t, "p2", createTestChildFn(t, "c2a", "r2a", false))
+	tk := toolkit.New("flat_tk", parent2, parent1, createTestParent(t, "empty"))
+
+	tools := tk.GetFlatTools()
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"p1__c1a", "p1__c1b", "p2__c2a"}, names, "Flat tools should be ordered by parent and child name")
+
+	assert.Equal(t, "desc_c1a", tools[0].Description)
+	assert.Equal(t, "p1", tools[0].Parent)
+	assert.Equal(t, "c1a", tools[0].Child)
+
+	raw, err := json.Marshal(tools[0].InputSchema)
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+	assert.Contains(t, schema["properties"], "val", "Flat tools should carry the child's input schema")
+	assert.NotContains(t, schema, "$schema")
+}
+
+func TestHandleFlatTool(t *testing.T) {
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true))
+	// Names containing the separator are resolved against the registered children
+	parent2 := createTestParent(t, "p1__x", createTestChildFn(t, "y", "rxy", false))
+	tk := toolkit.New("flat_tk", parent1, parent2)
+
+	resp, err := tk.HandleFlatTool(context.Background(), "p1__c1a", json.RawMessage(`{"val":"v"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "c1a", resp.Name)
+	assert.Equal(t, testResp{Res: "r1a:v"}, resp.Result)
+
+	resp, err = tk.HandleFlatTool(context.Background(), "p1__x__y", json.RawMessage(`{"val":"v"}`))
+	require.NoError(t, err)
+	assert.Equal(t, testResp{Res: "rxy:v"}, resp.Result)
+
+	// Handler errors and schema violations use the hierarchical error handling
+	resp, err = tk.HandleFlatTool(context.Background(), "p1__c1err", json.RawMessage(`{}`))
+	require.NoError(t, err)
+	tkErr, ok := resp.Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected a ToolKitError response, got %#v", resp)
+	assert.Equal(t, "handler_execution_error", tkErr.Code)
+
+	resp, err = tk.HandleFlatTool(context.Background(), "p1__c1a", json.RawMessage(`{"val":1}`))
+	require.NoError(t, err)
+	tkErr, ok = resp.Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected a ToolKitError response, got %#v", resp)
+	assert.Equal(t, "invalid_arguments", tkErr.Code)
+
+	// Unknown tools are reported as errors
+	for _, name := range []string{"p1__missing", "missing__c1a", "c1a", "p1"} {
+		resp, err = tk.HandleFlatTool(context.Background(), name, json.RawMessage(`{}`))
+		require.Error(t, err, "Tool %q should not resolve", name)
+		require.ErrorAs(t, err, &tkErr)
+		assert.Equal(t, "tool_not_found", tkErr.Code)
+		assert.Equal(t, err, resp.Err())
+		assert.Equal(t, toolkit.StatusError, resp.Status)
+	}
+}
+
+func TestHandleFlatTool_AppliesToolkitTimeout(t *testing.T) {
+	slow := toolkit.NewChild("slow", "desc_slow", func(ctx context.Context, args testArgs) (interface{}, error) {
+		time.Sleep(200 * time.Millisecond)
+		return testResp{Res: "late"}, nil
+	})
+	tk := toolkit.NewWithOptions("flat_tk", []toolkit.Option{toolkit.WithTimeout(20 * time.Millisecond)}, createTestParent(t, "p1", slow))
+
+	resp, err := tk.HandleFlatTool(context.Background(), "p1__slow", json.RawMessage(`{}`))
+	require.NoError(t, err)
+	tkErr, ok := resp.Err().(toolkit.ToolKitError)
+	require.True(t, ok, "Expected a ToolKitError response, got %#v", resp)
+	assert.Equal(t, "timeout", tkErr.Code)
+}
+
+// --- Test Streaming Events ---
+
+// eventSummary renders an event as "type:parent[.child]" for order assertions.
+func eventSummary(ev toolkit.Event) string {
+	switch ev.Type {
+	case toolkit.EventToolkitDone:
+		return string(ev.Type)
+	case toolkit.EventParentStarted:
+		return fmt.Sprintf("%s:%s", ev.Type, ev.Parent)
+	default:
+		return fmt.Sprintf("%s:%s.%s", ev.Type, ev.Parent, ev.Child)
+	}
+}
+
+func TestHandleToolKitStream_Sequential(t *testing.T) {
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true))
+	tk := toolkit.New("stream_tk", parent1)
+
+	input := `{"name":"stream_tk","parents":[
+		{"name":"p1","childs":[{"name":"c1a","args":{"val":"x"}},{"name":"c1err","args":{}},{"name":"missing","args":{}}]},
+		{"name":"nope","childs":[]}
+	]}`
+
+	var events []toolkit.Event
+	resp, err := tk.HandleToolKitStream(context.Background(), json.RawMessage(input), func(ev toolkit.Event) {
+		events = append(events, ev)
+	})
+	require.NoError(t, err)
+
+	summaries := make([]string, 0, len(events))
+	for _, ev := range events {
+		summaries = append(summaries, eventSummary(ev))
+		assert.False(t, ev.Time.IsZero(), "Events should carry a timestamp")
+	}
+	assert.Equal(t, []string{
+		"parent_started:p1",
+		"child_started:p1.c1a", "child_finished:p1.c1a",
+		"child_started:p1.c1err", "child_finished:p1.c1err",
+		"child_started:p1.missing", "child_finished:p1.missing",
+		"parent_started:nope",
+		"child_finished:nope._parent_error",
+		"toolkit_done",
+	}, summaries)
+
+	assert.Equal(t, testResp{Res: "r1a:x"}, events[2].Response.Result)
+	assert.NoError(t, events[2].Err)
+	assert.Equal(t, 1, events[4].ChildIndex)
+	assert.Error(t, events[4].Err, "Failed children should report their error")
+	assert.Error(t, events[6].Err)
+	assert.Equal(t, 1, events[7].ParentIndex)
+	assert.Equal(t, 1, events[8].ParentIndex)
+	assert.Error(t, events[8].Err)
+
+	done := events[len(events)-1]
+	require.NotNil(t, done.Result)
+	assert.Equal(t, resp, *done.Result)
+}
+
+func TestHandleToolKitStream_ConcurrentReportsFastChildrenFirst(t *testing.T) {
+	slow := toolkit.NewChild("slow", "desc_slow", func(ctx context.Context, args testArgs) (interface{}, error) {
+		time.Sleep(150 * time.Millisecond)
+		return testResp{Res: "slow"}, nil
+	})
+	parent := createTestParent(t, "p1", slow, createTestChildFn(t, "fast", "rf", false))
+	tk := toolkit.NewWithOptions("stream_tk", []toolkit.Option{toolkit.WithExecutionMode(toolkit.ExecutionConcurrent)}, parent)
+
+	var inCallback, overlaps int32
+	var finished []string
+	var fastFinishedAt time.Duration
+	start := time.Now()
+	_, err := tk.HandleToolKitStream(context.Background(),
+		json.RawMessage(`{"name":"stream_tk","parents":[{"name":"p1","childs":[{"name":"slow","args":{}},{"name":"fast","args":{}}]}]}`),
+		func(ev toolkit.Event) {
+			if atomic.AddInt32(&inCallback, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			defer atomic.AddInt32(&inCallback, -1)
+			if ev.Type == toolkit.EventChildFinished {
+				finished = append(finished, ev.Child)
+				if ev.Child == "fast" {
+					fastFinishedAt = time.Since(start)
+				}
+			}
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"fast", "slow"}, finished, "Fast children should be reported before slow ones finish")
+	assert.Less(t, fastFinishedAt, 100*time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(&overlaps), "The event handler must never be called concurrently")
+}
+
+func TestHandleToolKitStream_ParseError(t *testing.T) {
+	tk := toolkit.New("stream_tk")
+
+	var events []toolkit.Event
+	_, err := tk.HandleToolKitStream(context.Background(), json.RawMessage(`{broken`), func(ev toolkit.Event) {
+		events = append(events, ev)
+	})
+	require.Error(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, toolkit.EventToolkitDone, events[0].Type)
+	assert.Equal(t, err, events[0].Err)
+}
+
+func TestContextWithEventHandler_FlatTool(t *testing.T) {
+	tk := toolkit.New("stream_tk", createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false)))
+
+	var types []toolkit.EventType
+	ctx := toolkit.ContextWithEventHandler(context.Background(), func(ev toolkit.Event) {
+		types = append(types, ev.Type)
+	})
+	_, err := tk.HandleFlatTool(ctx, "p1__c1a", json.RawMessage(`{"val":"x"}`))
+	require.NoError(t, err)
+	assert.Equal(t, []toolkit.EventType{toolkit.EventParentStarted, toolkit.EventChildStarted, toolkit.EventChildFinished, toolkit.EventToolkitDone}, types)
+
+	// Unknown tools fail before any child starts, but still finish the request
+	var done []toolkit.Event
+	ctx = toolkit.ContextWithEventHandler(context.Background(), func(ev toolkit.Event) {
+		done = append(done, ev)
+	})
+	_, err = tk.HandleFlatTool(ctx, "p1__ghost", json.RawMessage(`{}`))
+	require.Error(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, toolkit.EventToolkitDone, done[0].Type)
+	assert.Equal(t, err, done[0].Err)
+	require.NotNil(t, done[0].Result)
+}
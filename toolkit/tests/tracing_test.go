@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/h-ess/ai-toolkit/toolkit"
+	tkotel "github.com/h-ess/ai-toolkit/toolkit/otel"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// --- Tracing Test Helpers ---
+
+// newTestTracerProvider returns a tracer provider recording spans in an in-memory exporter.
+func newTestTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider, exporter
+}
+
+// spanAttrs returns the attributes of a span as a map.
+func spanAttrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// spanByName returns the span with the given name.
+func spanByName(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "no span named %q", name)
+	return tracetest.SpanStub{}
+}
+
+// --- Test Tracing ---
+
+func TestTracing_SpanTree(t *testing.T) {
+	provider, exporter := newTestTracerProvider(t)
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true))
+	tk := toolkit.NewWithOptions("trace_tk", []toolkit.Option{toolkit.WithTracer(tkotel.NewTracer(tkotel.WithTracerProvider(provider)))}, parent1)
+
+	// The toolkit span is a child of the span of the incoming context
+	ctx, incoming := provider.Tracer("test").Start(context.Background(), "incoming")
+	input := `{"name":"trace_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"x"}},{"name":"c1err","args":{}}]}]}`
+	_, err := tk.HandleToolKit(ctx, json.RawMessage(input))
+	require.NoError(t, err)
+	incoming.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 5)
+	root := spanByName(t, spans, "incoming")
+	toolkitSpan := spanByName(t, spans, "toolkit trace_tk")
+	parentSpan := spanByName(t, spans, "parent p1")
+	okSpan := spanByName(t, spans, "child p1.c1a")
+	errSpan := spanByName(t, spans, "child p1.c1err")
+
+	assert.Equal(t, root.SpanContext.SpanID(), toolkitSpan.Parent.SpanID())
+	assert.Equal(t, toolkitSpan.SpanContext.SpanID(), parentSpan.Parent.SpanID())
+	assert.Equal(t, parentSpan.SpanContext.SpanID(), okSpan.Parent.SpanID())
+	assert.Equal(t, parentSpan.SpanContext.SpanID(), errSpan.Parent.SpanID())
+	assert.Equal(t, root.SpanContext.TraceID(), errSpan.SpanContext.TraceID())
+
+	attrs := spanAttrs(toolkitSpan)
+	assert.Equal(t, "trace_tk", attrs["toolkit.name"].AsString())
+	assert.NotEmpty(t, attrs["toolkit.call_id"].AsString())
+	assert.Equal(t, int64(2), attrs["toolkit.children.count"].AsInt64())
+	assert.Equal(t, int64(1), attrs["toolkit.children.failed"].AsInt64())
+
+	attrs = spanAttrs(okSpan)
+	assert.Equal(t, "c1a", attrs["toolkit.child.name"].AsString())
+	assert.Equal(t, "p1", attrs["toolkit.parent.name"].AsString())
+	assert.Equal(t, int64(len(`{"val":"x"}`)), attrs["toolkit.child.args_size"].AsInt64())
+	assert.Equal(t, int64(len(`{"res":"r1a:x"}`)), attrs["toolkit.child.result_size"].AsInt64())
+	assert.Equal(t, "ok", attrs["toolkit.status"].AsString())
+	assert.Equal(t, codes.Unset, okSpan.Status.Code)
+
+	attrs = spanAttrs(errSpan)
+	assert.Equal(t, "handler_execution_error", attrs["toolkit.error.code"].AsString())
+	assert.Equal(t, "error", attrs["toolkit.status"].AsString())
+	assert.Equal(t, codes.Error, errSpan.Status.Code)
+}
+
+func TestTracing_RequestErrorsAndFlatTools(t *testing.T) {
+	provider, exporter := newTestTracerProvider(t)
+	tk := toolkit.NewWithOptions("trace_tk", []toolkit.Option{toolkit.WithTracer(tkotel.NewTracer(tkotel.WithTracerProvider(provider)))},
+		createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false)))
+
+	_, err := tk.HandleToolKit(context.Background(), json.RawMessage(`{broken`))
+	require.Error(t, err)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "invalid_input_json", spanAttrs(spans[0])["toolkit.error.code"].AsString())
+
+	exporter.Reset()
+	_, err = tk.HandleFlatTool(context.Background(), "p1__c1a", json.RawMessage(`{"val":"x"}`))
+	require.NoError(t, err)
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, spanByName(t, spans, "parent p1").SpanContext.SpanID(), spanByName(t, spans, "child p1.c1a").Parent.SpanID())
+}
+
+// spanRecorder is a toolkit.Tracer recording the spans it starts as "kind name: outcome".
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+type recordedSpan struct {
+	recorder *spanRecorder
+	start    toolkit.SpanStart
+}
+
+func (r *spanRecorder) StartSpan(ctx context.Context, start toolkit.SpanStart) (context.Context, toolkit.Span) {
+	return ctx, &recordedSpan{recorder: r, start: start}
+}
+
+func (s *recordedSpan) IsRecording() bool { return true }
+
+func (s *recordedSpan) End(end toolkit.SpanEnd) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, fmt.Sprintf("%s %s.%s: failed=%d result=%d err=%v",
+		s.start.Kind, s.start.Parent, s.start.Child, end.Failed, end.ResultSize, end.Err != nil))
+}
+
+func TestTracing_CustomTracer(t *testing.T) {
+	recorder := &spanRecorder{}
+	parent1 := createTestParent(t, "p1", createTestChildFn(t, "c1a", "r1a", false), createTestChildFn(t, "c1err", "", true))
+	tk := toolkit.NewWithOptions("trace_tk", []toolkit.Option{toolkit.WithTracer(recorder)}, parent1)
+
+	input := `{"name":"trace_tk","parents":[{"name":"p1","childs":[{"name":"c1a","args":{"val":"x"}},{"name":"c1err","args":{}}]}]}`
+	_, err := tk.HandleToolKit(context.Background(), json.RawMessage(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"child p1.c1a: failed=0 result=15 err=false",
+		"child p1.c1err: failed=0 result=-1 err=true",
+		"parent p1.: failed=1 result=-1 err=false",
+		"toolkit .: failed=1 result=-1 err=false",
+	}, recorder.spans)
+}
@@ -7,8 +7,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"sync"
+	"time"
 )
 
 // --- Toolkit Struct and Methods ---
@@ -18,8 +20,23 @@ import (
 // for generating descriptions, JSON schemas, and processing execution requests.
 // Each Toolkit instance maintains a registry of Parent tools identified by unique names.
 type Toolkit struct {
-	parents map[string]Parent // Registry of Parent implementations mapped by name
-	name    string            // Name of this toolkit instance
+	mu             sync.RWMutex      // Guards parents, which may change while requests are served
+	parents        map[string]Parent // Registry of Parent implementations mapped by name
+	name           string            // Name of this toolkit instance
+	mode           ExecutionMode     // How parents and children of a request are executed
+	maxConcurrency int               // Maximum number of concurrently running children (<= 0 means unbounded)
+	timeout        time.Duration     // Bound for a whole HandleToolKit call; zero means none
+	policy         ExecutionPolicy   // What happens to a request after a child fails
+	middleware     []Middleware      // Middleware run around every child execution
+	logger         *slog.Logger      // Logger of the toolkit; nil means slog.Default()
+	tracer         Tracer            // Starts the spans of every request; nil means none
+	metrics        Metrics           // Receives an observation per child invocation; nil means none
+
+	descriptionBudget DescriptionBudget // Size limit of GetToolkitDescription; zero means none
+
+	listenersMu  sync.Mutex                   // Guards listeners and nextListener
+	listeners    map[int]func(RegistryChange) // Change listeners registered with OnChange
+	nextListener int                          // ID of the next change listener
 }
 
 // New creates a new Toolkit instance with the provided name and parent toolkits.
@@ -44,22 +61,47 @@ type Toolkit struct {
 //	networkParent := toolkit.NewParent("network", "Network operations", httpFetchTool)
 //	toolkit := toolkit.New("my_toolkit", fileOpsParent, networkParent)
 func New(name string, parents ...Parent) *Toolkit {
-	parentMap := make(map[string]Parent, len(parents))
+	return NewWithOptions(name, nil, parents...)
+}
+
+// NewWithOptions creates a new Toolkit instance like New, and additionally applies
+// the provided options (execution mode, concurrency limit, timeout, ...) to it.
+//
+// Example:
+//
+//	tk := toolkit.NewWithOptions(
+//	    "my_toolkit",
+//	    []toolkit.Option{
+//	        toolkit.WithExecutionMode(toolkit.ExecutionConcurrent),
+//	        toolkit.WithMaxConcurrency(4),
+//	        toolkit.WithTimeout(30 * time.Second),
+//	    },
+//	    fileOpsParent, networkParent,
+//	)
+func NewWithOptions(name string, opts []Option, parents ...Parent) *Toolkit {
+	t := &Toolkit{
+		parents: make(map[string]Parent, len(parents)),
+		name:    name,
+		mode:    ExecutionSequential,
+	}
+	// Options are applied first so registration warnings use the configured logger
+	for _, opt := range opts {
+		if opt != nil {
+			opt(t)
+		}
+	}
+
 	for _, p := range parents {
 		if p == nil {
-			log.Println("Warning: nil parent provided to toolkit.New, skipping.")
+			t.Logger().Warn("Nil parent provided to toolkit.New, skipping")
 			continue
 		}
-		if _, exists := parentMap[p.GetName()]; exists {
-			log.Printf("Warning: Duplicate parent name '%s' detected in toolkit.New. Overwriting.", p.GetName())
+		if _, exists := t.parents[p.GetName()]; exists {
+			t.Logger().Warn("Duplicate parent name detected in toolkit.New, overwriting", LogKeyParent, p.GetName())
 		}
-		parentMap[p.GetName()] = p
-	}
-
-	return &Toolkit{
-		parents: parentMap,
-		name:    name,
+		t.parents[p.GetName()] = p
 	}
+	return t
 }
 
 // GetToolkitName returns the configured name of the toolkit instance.
@@ -69,27 +111,33 @@ func (t *Toolkit) GetToolkitName() string {
 	return t.name
 }
 
-// GetToolkitSchema returns a JSON schema representation for the toolkit's request structure.
-// The schema is provider-specific and currently supports "anthropic" (Claude) format,
-// which is used as the default for unsupported providers.
+// GetParents returns the registered parents ordered by name.
+// Schema providers use it to walk the toolkit's parents and their children.
+func (t *Toolkit) GetParents() []Parent {
+	return t.sortedParents()
+}
+
+// GetToolkitSchema returns the tool definition of the toolkit for a schema provider.
+// The built-in providers are:
+//   - "anthropic": The Claude input schema (see GenerateToolkitSchema)
+//   - "openai": A strict-mode OpenAITool definition (see GenerateOpenAITool)
+//   - "gemini": A Gemini / Vertex AI function declaration (see GenerateGeminiFunctionDeclaration)
+//
+// Applications add their own providers with RegisterSchemaProvider.
 //
 // Parameters:
-//   - provider: The target provider identifier (e.g., "anthropic" for Claude)
+//   - provider: The name of a registered schema provider (e.g., "anthropic" for Claude)
 //
 // Returns:
-//   - A JSON schema object suitable for the specified provider
-//
-// The schema includes the full structure of the ToolKit request format, including
-// definitions for parents and children, and is suitable for direct use with LLM
-// tool registration endpoints.
-func (t *Toolkit) GetToolkitSchema(provider string) interface{} {
-	switch provider {
-	case "anthropic":
-		return GetToolKitSchemaForAnthropic()
-	default:
-		log.Printf("Warning: Unsupported schema provider '%s', defaulting to Anthropic schema", provider)
-		return GetToolKitSchemaForAnthropic()
+//   - The tool definition built by the provider, suitable for direct use with LLM tool registration endpoints
+//   - error: An "unknown_schema_provider" ToolKitError if no provider is registered under that name,
+//     or the error returned by the provider
+func (t *Toolkit) GetToolkitSchema(provider string) (interface{}, error) {
+	p, ok := lookupSchemaProvider(provider)
+	if !ok {
+		return nil, unknownProviderError(provider)
 	}
+	return p.Schema(t)
 }
 
 // GetToolkitDescription generates a human-readable XML-like description of the toolkit structure.
@@ -99,7 +147,12 @@ func (t *Toolkit) GetToolkitSchema(provider string) interface{} {
 // The description includes:
 //   - The toolkit name and a general explanation of the toolkit structure
 //   - A list of all parents with their descriptions
-//   - For each parent, a list of its child tools with descriptions and input schemas
+//   - For each parent, a list of its child tools with descriptions and input schemas,
+//     and the output schemas of children implementing TypedChild
+//
+// Parents and children are listed in name order, so the description is identical across
+// calls and can be cached by providers. When a DescriptionBudget is set (see
+// WithDescriptionBudget), the schemas are shortened until the description fits.
 //
 // Returns:
 //   - A formatted string containing the full toolkit description
@@ -107,32 +160,47 @@ func (t *Toolkit) GetToolkitSchema(provider string) interface{} {
 // This description is designed to be understood by LLMs for effective tool use
 // and follows a consistent XML-like format that highlights the hierarchical structure.
 func (t *Toolkit) GetToolkitDescription() string {
+	limit := t.descriptionBudget.limit()
+	var desc string
+	for detail := detailFull; detail <= detailNoOutputSchemas; detail++ {
+		desc = t.describe(detail)
+		if limit == 0 || len(desc) <= limit {
+			return desc
+		}
+	}
+	t.Logger().Warn("Toolkit description exceeds its budget", "bytes", len(desc), "limit", limit)
+	return desc
+}
+
+// describe generates the toolkit description with the child schemas at the given detail.
+func (t *Toolkit) describe(detail descriptionDetail) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("In this environment, you have access to the following <toolkit name=\"%s\">:\n", t.name))
 	sb.WriteString("A <toolkit> is a collection of <parents>, a <parent> is a collection of <childs>.\n")
+	sb.WriteString("A child argument may use the result of another child of the same request: {\"$ref\": \"<parent>.<child>#<n>/<json pointer>\"}, where <n> counts the calls of that child in the request from 0.\n")
 	sb.WriteString("Below is the list of available <parents> and their <childs>:\n")
 
-	for _, parent := range t.parents {
+	for _, parent := range t.sortedParents() {
 		sb.WriteString(fmt.Sprintf("<parent name=\"%s\" description=\"%s\"></parent>\n", parent.GetName(), parent.GetDescription()))
 
-		children := parent.GetChildren()
-		if len(children) > 0 {
-			// TODO: Maybe sort children by name?
-			for _, child := range children {
-				schema := child.GetInputSchema()
-				schemaBytes, err := json.Marshal(schema)
-				schemaStr := "schema_error"
-				if err == nil {
-					schemaStr = string(schemaBytes)
+		for _, child := range sortedChildren(parent) {
+			schemaStr := "schema_error"
+			if schemaBytes, err := describeSchema(child.GetInputSchema(), detail); err == nil {
+				schemaStr = string(schemaBytes)
+			} else {
+				t.Logger().Error("Error marshaling child schema", LogKeyParent, parent.GetName(), LogKeyChild, child.GetName(), LogKeyError, err.Error())
+			}
+			outputStr := ""
+			if output := OutputSchemaOf(child); output != nil && detail < detailNoOutputSchemas {
+				if outputBytes, err := describeSchema(output, detail); err == nil {
+					outputStr = fmt.Sprintf("<output_schema>%s</output_schema>", outputBytes)
 				} else {
-					log.Printf("Error marshaling schema for %s.%s: %v", parent.GetName(), child.GetName(), err)
+					t.Logger().Error("Error marshaling child output schema", LogKeyParent, parent.GetName(), LogKeyChild, child.GetName(), LogKeyError, err.Error())
 				}
-				sb.WriteString(fmt.Sprintf("<child name=\"%s\" description=\"%s\"><input_schema>%s</input_schema></child>\n", child.GetName(), child.GetDescription(), schemaStr))
 			}
-			sb.WriteString("</parent>\n")
-		} else {
-			sb.WriteString("</parent>\n")
+			sb.WriteString(fmt.Sprintf("<child name=\"%s\" description=\"%s\"><input_schema>%s</input_schema>%s</child>\n", child.GetName(), child.GetDescription(), schemaStr, outputStr))
 		}
+		sb.WriteString("</parent>\n")
 		sb.WriteString("**NOTE**: A child tool cannot be invoked directly, the parent tool must be invoked first via its parent.\n")
 	}
 	sb.WriteString("</toolkit>")
@@ -160,65 +228,157 @@ func (t *Toolkit) GetToolkitDescription() string {
 //   - If child tools fail, their errors are included in the appropriate child responses
 //
 // This enables clients to process both successful and failed operations in a consistent way.
+// To observe progress while the request runs, see HandleToolKitStream.
 func (t *Toolkit) HandleToolKit(ctx context.Context, input json.RawMessage) (ToolKitResponse, error) {
+	ctx, logger := t.requestContext(ctx)
+	ctx, span := t.startToolkitSpan(ctx)
 	tkRequest, err := t.parseToolKitInput(input)
 	if err != nil {
 		// Return a structured error response for parsing errors
-		log.Printf("Error parsing toolkit input: %v", err)
+		inputErr := NewError(CodeInvalidInputJSON, err.Error())
+		logger.Warn("Invalid toolkit request", errorAttrs(inputErr)...)
 		errResp := ToolKitResponse{
 			Name: "toolkit_request_parse_error",
 			Responses: []ParentResponse{
 				{
 					Name: "_parse_error",
 					ChildsResponses: []ChildResponse{
-						{Name: "_input_error", Response: NewError("invalid_input_json", err.Error())},
+						NewChildError("_input_error", inputErr),
 					},
 				},
 			},
 		}
+		endToolkitSpan(span, errResp, inputErr)
+		EmitEvent(ctx, Event{Type: EventToolkitDone, Result: &errResp, Err: err})
 		return errResp, err
 	}
 
 	// Pass the parsed request and context to the internal toolkit processor
-	return t.processToolKit(ctx, tkRequest)
+	resp, err := t.processToolKit(ctx, tkRequest)
+	endToolkitSpan(span, resp, err)
+	EmitEvent(ctx, Event{Type: EventToolkitDone, Result: &resp, Err: err})
+	return resp, err
 }
 
 // processToolKit orchestrates the execution of tools based on a parsed request.
 // It routes each parent request to the appropriate Parent instance and collects
 // their responses into a unified structure.
 //
+// In ExecutionConcurrent mode every parent runs in its own goroutine; responses are
+// written to their request index so the response order always matches the request.
+// The toolkit timeout (WithTimeout or RequestOptions.Timeout) bounds the whole request.
+// The execution policy (WithExecutionPolicy or RequestOptions.Policy) decides whether
+// children still start after a failure, and whether successful children are rolled back.
+//
 // This is an internal method used by HandleToolKit and shouldn't be called directly.
 func (t *Toolkit) processToolKit(ctx context.Context, toolkitRequest ToolKit) (ToolKitResponse, error) {
 	tlResponse := ToolKitResponse{
 		Name: t.GetToolkitName(),
 	}
 
+	logger := LoggerFromContext(ctx)
+	defer func(start time.Time) {
+		logger.Debug("Toolkit request finished", LogKeyDuration, time.Since(start))
+	}(time.Now())
+
 	if len(toolkitRequest.ToolKitParents) == 0 {
-		return tlResponse, NewError("no_toolkit_parents", "No toolkit parents specified in the request")
+		return tlResponse, NewError(CodeNoToolkitParents, "No toolkit parents specified in the request")
 	}
 
-	for _, parentReq := range toolkitRequest.ToolKitParents {
-		parent, ok := t.parents[parentReq.Name]
-		if !ok {
-			log.Printf("Toolkit: Requested parent '%s' not found", parentReq.Name)
-			errResp := ParentResponse{
-				Name: parentReq.Name,
-				ChildsResponses: []ChildResponse{
-					{Name: "_parent_error", Response: NewError("parent_not_found", fmt.Sprintf("Parent toolkit '%s' not registered", parentReq.Name))},
-				},
-			}
-			tlResponse.AddResponse(errResp)
-			continue
+	if timeout := overrideTimeout(t.timeout, requestOptionsFrom(ctx).Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	policy := t.policy
+	if override := requestOptionsFrom(ctx).Policy; override != "" {
+		policy = override
+	}
+	if !policy.valid() {
+		return tlResponse, NewError(CodeUnknownExecutionPolicy, fmt.Sprintf("Execution policy '%s' is not supported", policy))
+	}
+
+	exec := newExecution(t.mode, t.maxConcurrency, policy)
+	exec.mw = t.middleware
+	exec.tracer = t.tracer
+	exec.metrics, exec.toolkit = t.metrics, t.name
+	ctx = withExecution(ctx, exec)
+
+	// Requests whose arguments reference other children's results run as a dependency DAG
+	if plan := t.planDataFlow(toolkitRequest); plan != nil {
+		tlResponse = t.processDataFlow(ctx, toolkitRequest, plan)
+		exec.rollback(ctx, &tlResponse)
+		return tlResponse, nil
+	}
+
+	if !exec.concurrent() {
+		for i, parentReq := range toolkitRequest.ToolKitParents {
+			tlResponse.AddResponse(t.handleParent(ctx, i, parentReq))
 		}
+		exec.rollback(ctx, &tlResponse)
+		return tlResponse, nil
+	}
 
-		// Pass context down to HandleChildren
-		parentResponse := parent.HandleChildren(ctx, parentReq.ToolKitChilds)
-		tlResponse.AddResponse(parentResponse)
+	tlResponse.Responses = make([]ParentResponse, len(toolkitRequest.ToolKitParents))
+	var wg sync.WaitGroup
+	for i, parentReq := range toolkitRequest.ToolKitParents {
+		wg.Add(1)
+		go func(i int, parentReq ToolKitParent) {
+			defer wg.Done()
+			tlResponse.Responses[i] = t.handleParent(ctx, i, parentReq)
+		}(i, parentReq)
 	}
+	wg.Wait()
 
+	exec.rollback(ctx, &tlResponse)
 	return tlResponse, nil
 }
 
+// handleParent routes a single parent request to the registered Parent instance.
+// If the parent is not registered, a parent_not_found error response is returned instead.
+// index is the position of the parent in the request, reported in progress events.
+func (t *Toolkit) handleParent(ctx context.Context, index int, parentReq ToolKitParent) (resp ParentResponse) {
+	ctx, span := startParentSpan(withEventParent(ctx, index), parentReq.Name, len(parentReq.ToolKitChilds))
+	defer func() { endParentSpan(span, resp) }()
+	EmitEvent(ctx, Event{Type: EventParentStarted, Parent: parentReq.Name, ParentIndex: index})
+
+	parent, ok := t.parent(parentReq.Name)
+	if !ok {
+		notFound := t.parentNotFound(parentReq.Name)
+		errResp := NewChildError("_parent_error", notFound)
+		LoggerFromContext(ctx).Warn("Requested parent not found", append([]interface{}{LogKeyParent, parentReq.Name}, errorAttrs(notFound)...)...)
+		executionFrom(ctx).fail(parentReq.Name, errResp.Name)
+		observeChild(ctx, ChildObservation{Parent: parentReq.Name, ErrorCode: CodeParentNotFound})
+		EmitEvent(ctx, childFinishedEvent(parentReq.Name, 0, errResp))
+		return ParentResponse{
+			Name:            parentReq.Name,
+			ChildsResponses: []ChildResponse{errResp},
+		}
+	}
+
+	// Custom Parent implementations don't apply the execution policy to their children,
+	// so it is applied to the parent as a whole
+	exec := executionFrom(ctx)
+	if exec.stopped() {
+		resp = ParentResponse{Name: parentReq.Name}
+		for i, childReq := range parentReq.ToolKitChilds {
+			resp.AddResponse(exec.skippedResponse(childReq.Name))
+			EmitEvent(ctx, childFinishedEvent(parentReq.Name, i, resp.ChildsResponses[i]))
+		}
+		return resp
+	}
+
+	// Pass context down to HandleChildren
+	resp = parent.HandleChildren(ctx, parentReq.ToolKitChilds)
+	for _, childResp := range resp.ChildsResponses {
+		if childResp.Failed() {
+			exec.fail(parentReq.Name, childResp.Name)
+		}
+	}
+	return resp
+}
+
 // parseToolKitInput parses the incoming JSON request into a structured format.
 // It validates that the JSON conforms to the expected ToolKit structure.
 //
@@ -0,0 +1,135 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file defines the tracing hook: every HandleToolKit call produces a span tree (toolkit,
+// parents, children) that shows which tool of a multi-tool turn caused latency.
+package toolkit
+
+import "context"
+
+// SpanKind identifies the level of a span in the span tree of a request.
+type SpanKind string
+
+const (
+	SpanToolkit SpanKind = "toolkit" // A HandleToolKit or HandleFlatTool call
+	SpanParent  SpanKind = "parent"  // A parent executing within a request
+	SpanChild   SpanKind = "child"   // A child execution
+)
+
+// SpanStart describes a span when it starts.
+type SpanStart struct {
+	Kind     SpanKind // Level of the span
+	Toolkit  string   // Toolkit name
+	CallID   string   // Call ID of the request (see ContextWithCallID)
+	Parent   string   // Parent name; empty for toolkit spans
+	Child    string   // Child name; set for child spans
+	Children int      // Number of requested children; set for parent spans
+	ArgsSize int      // Size of the raw arguments in bytes; set for child spans
+}
+
+// SpanEnd describes the outcome of a span when it ends.
+type SpanEnd struct {
+	Children   int   // Number of children in the response; set for toolkit spans
+	Failed     int   // Number of failed children; set for toolkit and parent spans
+	Attempts   int   // Number of attempts of a retried child; zero without retries
+	ResultSize int   // Size of the JSON encoded result of a child in bytes; negative when not computed
+	Err        error // Error of the request (toolkit spans) or of the child (child spans); nil on success
+}
+
+// Tracer starts the spans of the requests of a toolkit: a toolkit span per request, a parent
+// span per requested parent below it, and a child span per child below its parent span.
+// Implementations must be safe for concurrent use.
+//
+// The toolkit/otel package provides an OpenTelemetry implementation.
+type Tracer interface {
+	// StartSpan starts a span below the span carried by ctx and returns a copy of ctx
+	// carrying the new span.
+	StartSpan(ctx context.Context, start SpanStart) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// IsRecording reports whether the span records its outcome. Result sizes cost a
+	// marshal, so they are only computed for recording spans or when metrics are set.
+	IsRecording() bool
+	// End records the outcome of the span and ends it.
+	End(end SpanEnd)
+}
+
+// noopSpan is the Span of toolkits without a Tracer.
+type noopSpan struct{}
+
+func (noopSpan) IsRecording() bool { return false }
+func (noopSpan) End(SpanEnd)       {}
+
+// startSpan starts a span with tracer, or a no-op span if tracer is nil.
+func startSpan(ctx context.Context, tracer Tracer, start SpanStart) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.StartSpan(ctx, start)
+}
+
+// startToolkitSpan starts the root span of a HandleToolKit or HandleFlatTool call,
+// as a child of the span in ctx.
+func (t *Toolkit) startToolkitSpan(ctx context.Context) (context.Context, Span) {
+	return startSpan(ctx, t.tracer, SpanStart{Kind: SpanToolkit, Toolkit: t.name, CallID: CallIDFromContext(ctx)})
+}
+
+// endToolkitSpan records the outcome of a request on its span and ends it.
+func endToolkitSpan(span Span, resp ToolKitResponse, err error) {
+	end := SpanEnd{ResultSize: -1, Err: err}
+	for _, parentResp := range resp.Responses {
+		for _, childResp := range parentResp.ChildsResponses {
+			end.Children++
+			if childResp.Failed() {
+				end.Failed++
+			}
+		}
+	}
+	span.End(end)
+}
+
+// startParentSpan starts the span of a parent executing within a request.
+func startParentSpan(ctx context.Context, parent string, children int) (context.Context, Span) {
+	e := executionFrom(ctx)
+	return startSpan(ctx, e.tracer, SpanStart{
+		Kind:     SpanParent,
+		Toolkit:  e.toolkit,
+		CallID:   CallIDFromContext(ctx),
+		Parent:   parent,
+		Children: children,
+	})
+}
+
+// endParentSpan records the failed children of a parent on its span and ends it.
+func endParentSpan(span Span, resp ParentResponse) {
+	end := SpanEnd{ResultSize: -1}
+	for _, childResp := range resp.ChildsResponses {
+		if childResp.Failed() {
+			end.Failed++
+		}
+	}
+	span.End(end)
+}
+
+// startChildSpan starts the span of a child execution.
+func startChildSpan(ctx context.Context, parent string, req ToolKitChild) (context.Context, Span) {
+	e := executionFrom(ctx)
+	return startSpan(ctx, e.tracer, SpanStart{
+		Kind:     SpanChild,
+		Toolkit:  e.toolkit,
+		CallID:   CallIDFromContext(ctx),
+		Parent:   parent,
+		Child:    req.Name,
+		ArgsSize: len(req.Args),
+	})
+}
+
+// endChildSpan records the result size or the error of a child execution and ends its span.
+// resultSize is negative when it was not computed.
+func endChildSpan(span Span, resp ChildResponse, resultSize int) {
+	end := SpanEnd{Attempts: resp.Attempts, ResultSize: resultSize}
+	if resp.Failed() {
+		end.Err, end.ResultSize = resp.Err(), -1
+	}
+	span.End(end)
+}
@@ -4,7 +4,9 @@
 package toolkit
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/invopop/jsonschema"
@@ -53,12 +55,88 @@ type ParentResponse struct {
 	ChildsResponses []ChildResponse `json:"childsResponses,omitempty"`
 }
 
+// ChildStatus is the outcome of a child execution, as reported in ChildResponse.Status.
+type ChildStatus string
+
+const (
+	StatusOK      ChildStatus = "ok"      // The child succeeded; Result holds its result
+	StatusError   ChildStatus = "error"   // The child failed; Error describes the failure
+	StatusSkipped ChildStatus = "skipped" // The child was not executed because of an earlier failure (see ExecutionPolicy)
+	StatusTimeout ChildStatus = "timeout" // The child did not finish before its deadline
+)
+
 // ChildResponse represents the response from executing a single child tool.
-// The Response field can contain either the successful result (as returned by the tool's handler)
-// or a ToolKitError if an error occurred during execution, providing a consistent error handling mechanism.
+// Status tells success and failure apart: successful children carry the result returned by the
+// tool's handler in Result, failed ones a ToolKitError in Error, so both shapes serialize reliably.
+// Attempts counts the executions of children with a RetryPolicy and is zero for all others.
+// Use NewChildResult and NewChildError to build responses in custom Parent implementations.
 type ChildResponse struct {
-	Name     string      `json:"name"`
-	Response interface{} `json:"response,omitempty"`
+	Name     string        `json:"name"`
+	Status   ChildStatus   `json:"status"`
+	Result   interface{}   `json:"result,omitempty"`
+	Error    *ToolKitError `json:"error,omitempty"`
+	Attempts int           `json:"attempts,omitempty"`
+}
+
+// NewChildResult creates the response of a successful child execution.
+func NewChildResult(name string, result interface{}) ChildResponse {
+	return ChildResponse{Name: name, Status: StatusOK, Result: result}
+}
+
+// NewChildError creates the response of a failed child execution. Errors other than
+// ToolKitError are converted, so every failure serializes with a code and a message:
+// context errors become "timeout" or "canceled", other errors "handler_execution_error".
+// The status is derived from the error code.
+func NewChildError(name string, err error) ChildResponse {
+	var tkErr ToolKitError
+	switch {
+	case errors.As(err, &tkErr):
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		errors.As(contextError(name, err), &tkErr)
+	default:
+		tkErr = ToolKitError{Code: CodeHandlerExecutionError, Message: err.Error(), cause: err}
+	}
+
+	status := StatusError
+	switch tkErr.Code {
+	case CodeSkipped:
+		status = StatusSkipped
+	case CodeTimeout:
+		status = StatusTimeout
+	}
+	return ChildResponse{Name: name, Status: status, Error: &tkErr}
+}
+
+// Failed reports whether the child did not succeed.
+func (cr ChildResponse) Failed() bool {
+	return cr.Error != nil
+}
+
+// Err returns the error of a failed child, or nil if it succeeded.
+func (cr ChildResponse) Err() error {
+	if cr.Error == nil {
+		return nil
+	}
+	return *cr.Error
+}
+
+// ChildError is a failed child of a ToolKitResponse, as returned by ToolKitResponse.Errors.
+type ChildError struct {
+	Parent     string       // Name of the parent
+	Child      string       // Name of the child
+	ChildIndex int          // Position of the child in ParentResponse.ChildsResponses
+	Status     ChildStatus  // Status of the child
+	Err        ToolKitError // Error of the child
+}
+
+// Error implements the error interface, prefixing the child error with the tool name.
+func (e ChildError) Error() string {
+	return fmt.Sprintf("%s.%s: %v", e.Parent, e.Child, e.Err)
+}
+
+// Unwrap returns the ToolKitError of the child, for use with errors.As.
+func (e ChildError) Unwrap() error {
+	return e.Err
 }
 
 // --- Tool Metadata Structures ---
@@ -67,12 +145,106 @@ type ChildResponse struct {
 
 // --- Error Handling ---
 
+// Error codes used by the toolkit. Tools may return any other code through NewError.
+const (
+	CodeInvalidArguments       = "invalid_arguments"        // Tool arguments don't match the expected schema
+	CodeHandlerExecutionError  = "handler_execution_error"  // The tool execution failed
+	CodeHandlerPanic           = "handler_panic"            // The tool panicked; Details hold the panic value and a trimmed stack
+	CodeChildNotFound          = "child_not_found"          // A requested child tool doesn't exist
+	CodeParentNotFound         = "parent_not_found"         // A requested parent doesn't exist
+	CodeToolNotFound           = "tool_not_found"           // A requested flat tool doesn't exist
+	CodeTimeout                = "timeout"                  // A tool did not finish before its child, parent or toolkit deadline
+	CodeCanceled               = "canceled"                 // The request context was canceled before a tool finished
+	CodeInvalidInputJSON       = "invalid_input_json"       // The toolkit request is not valid JSON
+	CodeNoToolkitParents       = "no_toolkit_parents"       // The toolkit request names no parents
+	CodeUnresolvedReference    = "unresolved_reference"     // A "$ref" argument cannot be resolved from an earlier result
+	CodeReferenceCycle         = "reference_cycle"          // "$ref" arguments reference each other in a cycle
+	CodeSkipped                = "skipped"                  // A tool was not executed because an earlier tool failed (see ExecutionPolicy)
+	CodeRolledBack             = "rolled_back"              // A successful tool was undone because an all-or-nothing request failed
+	CodeRollbackFailed         = "rollback_failed"          // A successful tool could not be undone after an all-or-nothing request failed
+	CodeUnknownSchemaProvider  = "unknown_schema_provider"  // GetToolkitSchema was called with an unregistered provider
+	CodeUnsupportedSchema      = "unsupported_schema"       // A child input schema cannot be expressed in the format of a schema provider
+	CodeInvalidSchema          = "invalid_schema"           // A child input schema is invalid, such as a pattern that is not a valid regular expression
+	CodeUnknownExecutionPolicy = "unknown_execution_policy" // A request selected an unknown ExecutionPolicy
+	CodeImmutableParent        = "immutable_parent"         // Children were added to or removed from a parent that is not a MutableParent
+)
+
 // ToolKitError provides a standardized structure for errors occurring within the toolkit framework.
 // It encapsulates both a machine-readable error code for programmatic handling and a human-readable
-// message for debugging and user feedback.
+// message for debugging and user feedback. For "invalid_arguments" errors produced by schema
+// validation, Violations lists every mismatch so the model can correct all of them at once.
+//
+// Violations, Details, Retryable and Hint are serialized with the error, so the model sees them
+// in tool results. The underlying error, if any, is not serialized but is available through
+// Unwrap, so errors.Is and errors.As see through errors wrapped by the toolkit.
+//
+// ToolKitError values are comparable with ==: the violations and details are kept behind a
+// pointer and read with the Violations and Details methods.
 type ToolKitError struct {
-	Code    string `json:"Code"`    // A machine-readable error code (e.g., "invalid_arguments", "handler_execution_error")
-	Message string `json:"Message"` // A human-readable description of the error
+	Code      string // A machine-readable error code (see the Code constants)
+	Message   string // A human-readable description of the error
+	Retryable bool   // Whether calling the tool again may succeed
+	Hint      string // Advice for the model on how to recover
+
+	extra *errorExtra // Violations and details, if any; a pointer keeps the struct comparable
+	cause error       // The underlying error, if any (see WithCause)
+}
+
+// errorExtra holds the uncomparable fields of a ToolKitError.
+type errorExtra struct {
+	violations []SchemaViolation
+	details    map[string]any
+}
+
+// toolKitErrorJSON is the serialized form of a ToolKitError.
+type toolKitErrorJSON struct {
+	Code       string            `json:"Code"`
+	Message    string            `json:"Message"`
+	Violations []SchemaViolation `json:"Violations,omitempty"`
+	Details    map[string]any    `json:"Details,omitempty"`
+	Retryable  bool              `json:"Retryable,omitempty"`
+	Hint       string            `json:"Hint,omitempty"`
+}
+
+// Violations returns the schema violations of the tool arguments, if any.
+func (e ToolKitError) Violations() []SchemaViolation {
+	if e.extra == nil {
+		return nil
+	}
+	return e.extra.violations
+}
+
+// Details returns the structured context of the failure, if any (see WithDetails).
+func (e ToolKitError) Details() map[string]any {
+	if e.extra == nil {
+		return nil
+	}
+	return e.extra.details
+}
+
+// MarshalJSON implements json.Marshaler, including the violations and details.
+func (e ToolKitError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toolKitErrorJSON{
+		Code:       e.Code,
+		Message:    e.Message,
+		Violations: e.Violations(),
+		Details:    e.Details(),
+		Retryable:  e.Retryable,
+		Hint:       e.Hint,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, restoring the violations and details.
+func (e *ToolKitError) UnmarshalJSON(data []byte) error {
+	var raw toolKitErrorJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = ToolKitError{Code: raw.Code, Message: raw.Message, Retryable: raw.Retryable, Hint: raw.Hint}
+	if raw.Violations != nil || raw.Details != nil {
+		e.extra = &errorExtra{violations: raw.Violations, details: raw.Details}
+	}
+	return nil
 }
 
 // Error implements the standard error interface for ToolKitError.
@@ -82,20 +254,82 @@ func (e ToolKitError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Unwrap returns the underlying error, so errors.Is and errors.As see through the ToolKitError.
+func (e ToolKitError) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is a ToolKitError with the same code, so errors can be matched
+// by code regardless of their message:
+//
+//	if errors.Is(err, toolkit.ToolKitError{Code: toolkit.CodeTimeout}) { ... }
+func (e ToolKitError) Is(target error) bool {
+	t, ok := target.(ToolKitError)
+	return ok && t.Code == e.Code
+}
+
+// ErrorOption configures a ToolKitError created with NewError.
+type ErrorOption func(*ToolKitError)
+
+// WithCause records the underlying error of a ToolKitError (see ToolKitError.Unwrap).
+func WithCause(err error) ErrorOption {
+	return func(e *ToolKitError) {
+		e.cause = err
+	}
+}
+
+// WithDetails attaches structured context to a ToolKitError (see ToolKitError.Details).
+func WithDetails(details map[string]any) ErrorOption {
+	return func(e *ToolKitError) {
+		e.extra = &errorExtra{violations: e.Violations(), details: details}
+	}
+}
+
+// WithViolations attaches the schema violations of the tool arguments to a ToolKitError
+// (see ToolKitError.Violations).
+func WithViolations(violations []SchemaViolation) ErrorOption {
+	return func(e *ToolKitError) {
+		e.extra = &errorExtra{violations: violations, details: e.Details()}
+	}
+}
+
+// WithRetryable marks whether calling the tool again may succeed.
+func WithRetryable(retryable bool) ErrorOption {
+	return func(e *ToolKitError) {
+		e.Retryable = retryable
+	}
+}
+
+// WithHint attaches advice for the model on how to recover from the error.
+func WithHint(hint string) ErrorOption {
+	return func(e *ToolKitError) {
+		e.Hint = hint
+	}
+}
+
 // NewError creates a new ToolKitError instance with the specified code and message.
 // This is the preferred way to create and return errors from tool implementations
-// to ensure consistent error handling across the toolkit.
+// to ensure consistent error handling across the toolkit. The toolkit's own codes
+// are listed as Code constants.
+//
+// Example:
 //
-// Common error codes:
-//   - "invalid_arguments": When tool arguments don't match the expected schema
-//   - "handler_execution_error": When the tool execution fails
-//   - "child_not_found": When a requested child tool doesn't exist
-//   - "parent_not_found": When a requested parent doesn't exist
-func NewError(code, message string) error {
-	return ToolKitError{
+//	return nil, toolkit.NewError("rate_limited", "The search API is rate limited",
+//	    toolkit.WithRetryable(true),
+//	    toolkit.WithHint("Wait a moment before searching again."),
+//	    toolkit.WithCause(err),
+//	)
+func NewError(code, message string, opts ...ErrorOption) error {
+	e := ToolKitError{
 		Code:    code,
 		Message: message,
 	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&e)
+		}
+	}
+	return e
 }
 
 // --- Response Helper Methods ---
@@ -107,6 +341,45 @@ func (tr *ToolKitResponse) AddResponse(pr ParentResponse) {
 	tr.Responses = append(tr.Responses, pr)
 }
 
+// Errors returns the failed children of the response, in response order.
+func (tr ToolKitResponse) Errors() []ChildError {
+	var errs []ChildError
+	for _, parentResp := range tr.Responses {
+		for i, childResp := range parentResp.ChildsResponses {
+			if childResp.Failed() {
+				errs = append(errs, ChildError{
+					Parent:     parentResp.Name,
+					Child:      childResp.Name,
+					ChildIndex: i,
+					Status:     childResp.Status,
+					Err:        *childResp.Error,
+				})
+			}
+		}
+	}
+	return errs
+}
+
+// HasErrors reports whether any child of the response failed.
+func (tr ToolKitResponse) HasErrors() bool {
+	for _, parentResp := range tr.Responses {
+		if parentResp.HasErrors() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasErrors reports whether any child of the parent response failed.
+func (pr ParentResponse) HasErrors() bool {
+	for _, childResp := range pr.ChildsResponses {
+		if childResp.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
 // AddResponse appends a ChildResponse to the ParentResponse's list of child responses.
 // This helper method is used by Parent implementations to build the response
 // structure during child tool execution.
@@ -125,6 +398,8 @@ func (pr *ParentResponse) AddResponse(cr ChildResponse) {
 // - required: Whether the field is required
 // - description: Field descriptions for documentation
 //
+// Struct schemas set additionalProperties to false, so NewChild rejects unknown argument properties.
+//
 // Example usage:
 //
 //	type MyArgs struct {
@@ -134,17 +409,18 @@ func (pr *ParentResponse) AddResponse(cr ChildResponse) {
 //	schema := GenerateSchema[MyArgs]()
 func GenerateSchema[T any]() interface{} {
 	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties:  true, // Allow additional properties in the generated schema
-		DoNotReference:             true, // Keep schema self-contained, no $refs
-		RequiredFromJSONSchemaTags: true, // Respect `jsonschema:"required"` tags
+		AllowAdditionalProperties:  false, // Reject unknown properties so argument validation catches typos
+		DoNotReference:             true,  // Keep schema self-contained, no $refs
+		RequiredFromJSONSchemaTags: true,  // Respect `jsonschema:"required"` tags
 	}
 	var v T                      // Create a zero value instance of the type T
 	return reflector.Reflect(&v) // Reflect on the zero value to get the schema
 }
 
-// GetToolKitSchemaForAnthropic generates the specific JSON schema structure
-// expected by Anthropic's Claude API for the top-level ToolKit request.
-// This schema is used when registering the toolkit with Anthropic's tool use capability.
+// GetToolKitSchemaForAnthropic generates the generic JSON schema structure of the
+// top-level ToolKit request, in the format expected by Anthropic's Claude API.
+// It does not know about registered tools, so `args` stays an opaque object;
+// prefer Toolkit.GetToolkitSchema, which embeds the real child argument schemas.
 func GetToolKitSchemaForAnthropic() interface{} {
 	return GenerateSchema[ToolKit]()
 }
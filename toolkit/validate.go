@@ -0,0 +1,555 @@
+// Package toolkit provides a hierarchical tool orchestration framework for AI-powered applications.
+// This file contains the JSON schema validator used to check child arguments against
+// their input schema before a handler is invoked.
+package toolkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+)
+
+// SchemaViolation describes a single mismatch between tool arguments and an input schema.
+// Pointer is a JSON pointer (RFC 6901) to the offending value; the empty string denotes
+// the arguments object itself.
+type SchemaViolation struct {
+	Pointer string `json:"Pointer"`
+	Message string `json:"Message"`
+}
+
+// ValidateArgs validates raw JSON arguments against a JSON schema and returns every
+// violation found, or nil if the arguments are valid. Empty or null arguments are
+// validated as an empty object, matching how NewChild treats them.
+//
+// The validator supports the keywords produced by GenerateSchema: type, enum, const,
+// properties, required, additionalProperties, patternProperties, items, length, size
+// and numeric bounds, pattern, and the allOf/anyOf/oneOf/not/if-then-else combinators.
+// References ($ref) and formats are not checked. Like json.Unmarshal, the validator accepts
+// null for properties that are not required. A pattern that is not a valid regular expression
+// is reported as a single violation of the arguments object.
+//
+// Custom Child implementations can call ValidateArgs from their Handle method to get
+// the same behavior as builder-made children.
+func ValidateArgs(schema *jsonschema.Schema, args json.RawMessage) []SchemaViolation {
+	if schema == nil {
+		return nil
+	}
+	patterns, err := compilePatterns(schema)
+	if err != nil {
+		return []SchemaViolation{{Pointer: "", Message: fmt.Sprintf("input schema is invalid: %v", err)}}
+	}
+	return validateArgs(schema, patterns, args)
+}
+
+// validateArgs validates raw JSON arguments against a schema whose patterns were compiled
+// with compilePatterns.
+func validateArgs(schema *jsonschema.Schema, patterns map[string]*regexp.Regexp, args json.RawMessage) []SchemaViolation {
+	dec := json.NewDecoder(bytes.NewReader(normalizeArgs(args)))
+	dec.UseNumber() // Keep numbers exact so integers can be told apart from floats
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return []SchemaViolation{{Pointer: "", Message: fmt.Sprintf("arguments are not valid JSON: %v", err)}}
+	}
+
+	v := &validator{patterns: patterns}
+	v.validate(schema, value, "")
+	return v.violations
+}
+
+// compilePatterns compiles the pattern and patternProperties regular expressions of schema
+// and all of its subschemas, so validation does not compile them on every call.
+func compilePatterns(schema *jsonschema.Schema) (map[string]*regexp.Regexp, error) {
+	patterns := make(map[string]*regexp.Regexp)
+	compile := func(pattern string) error {
+		if _, ok := patterns[pattern]; ok {
+			return nil
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q is not a valid regular expression: %w", pattern, err)
+		}
+		patterns[pattern] = re
+		return nil
+	}
+
+	visited := make(map[*jsonschema.Schema]bool)
+	var walk func(s *jsonschema.Schema) error
+	walk = func(s *jsonschema.Schema) error {
+		if s == nil || visited[s] {
+			return nil
+		}
+		visited[s] = true
+		if s.Pattern != "" {
+			if err := compile(s.Pattern); err != nil {
+				return err
+			}
+		}
+		for pattern := range s.PatternProperties {
+			if err := compile(pattern); err != nil {
+				return err
+			}
+		}
+		for _, sub := range subschemas(s) {
+			if err := walk(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(schema); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// subschemas returns the direct subschemas of schema.
+func subschemas(schema *jsonschema.Schema) []*jsonschema.Schema {
+	subs := []*jsonschema.Schema{schema.Not, schema.If, schema.Then, schema.Else, schema.Items,
+		schema.Contains, schema.AdditionalProperties, schema.PropertyNames, schema.ContentSchema}
+	subs = append(subs, schema.AllOf...)
+	subs = append(subs, schema.AnyOf...)
+	subs = append(subs, schema.OneOf...)
+	subs = append(subs, schema.PrefixItems...)
+	if schema.Properties != nil {
+		for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			subs = append(subs, pair.Value)
+		}
+	}
+	for _, group := range []map[string]*jsonschema.Schema{schema.PatternProperties, schema.DependentSchemas, schema.Definitions} {
+		for _, sub := range group {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// normalizeArgs maps empty and null arguments to an empty JSON object.
+func normalizeArgs(args json.RawMessage) json.RawMessage {
+	trimmed := bytes.TrimSpace(args)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return json.RawMessage("{}")
+	}
+	return args
+}
+
+// formatViolations renders violations as a single human-readable line for error messages.
+func formatViolations(violations []SchemaViolation) string {
+	parts := make([]string, 0, len(violations))
+	for _, v := range violations {
+		pointer := v.Pointer
+		if pointer == "" {
+			pointer = "(root)"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", pointer, v.Message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// validator walks a decoded JSON value alongside its schema and collects violations.
+type validator struct {
+	patterns   map[string]*regexp.Regexp // Compiled patterns of the schema (see compilePatterns)
+	violations []SchemaViolation
+}
+
+// addf records a violation at the given JSON pointer.
+func (v *validator) addf(pointer, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches reports whether value is valid against schema without recording violations.
+func (v *validator) matches(schema *jsonschema.Schema, value interface{}) bool {
+	probe := &validator{patterns: v.patterns}
+	probe.validate(schema, value, "")
+	return len(probe.violations) == 0
+}
+
+// validate checks value against schema, recording violations relative to pointer.
+func (v *validator) validate(schema *jsonschema.Schema, value interface{}, pointer string) {
+	if schema == nil || isBoolSchema(schema, true) {
+		return
+	}
+	if isBoolSchema(schema, false) {
+		v.addf(pointer, "no value is allowed here")
+		return
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		v.addf(pointer, "expected %s, got %s", schema.Type, jsonTypeOf(value))
+		return // Further keywords would only produce noise for a mistyped value
+	}
+	if len(schema.Enum) > 0 && !containsValue(schema.Enum, value) {
+		v.addf(pointer, "value must be one of %s", formatEnum(schema.Enum))
+	}
+	if schema.Const != nil && !jsonEqual(schema.Const, value) {
+		v.addf(pointer, "value must be %s", formatEnum([]any{schema.Const}))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, val, pointer)
+	case []interface{}:
+		v.validateArray(schema, val, pointer)
+	case string:
+		v.validateString(schema, val, pointer)
+	case json.Number:
+		v.validateNumber(schema, val, pointer)
+	}
+
+	v.validateCombinators(schema, value, pointer)
+}
+
+// validateObject checks the object keywords: required, properties,
+// patternProperties, additionalProperties and the property count bounds.
+func (v *validator) validateObject(schema *jsonschema.Schema, obj map[string]interface{}, pointer string) {
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			v.addf(childPointer(pointer, name), "required property is missing")
+		}
+	}
+	if schema.MinProperties != nil && uint64(len(obj)) < *schema.MinProperties {
+		v.addf(pointer, "object must have at least %d properties", *schema.MinProperties)
+	}
+	if schema.MaxProperties != nil && uint64(len(obj)) > *schema.MaxProperties {
+		v.addf(pointer, "object must have at most %d properties", *schema.MaxProperties)
+	}
+
+	for _, name := range sortedKeys(obj) {
+		value := obj[name]
+		propPointer := childPointer(pointer, name)
+		known := false
+
+		if schema.Properties != nil {
+			if propSchema, ok := schema.Properties.Get(name); ok {
+				known = true
+				// json.Unmarshal leaves optional fields, usually pointers, unset for null,
+				// and models often send null for arguments they omit
+				if value == nil && !slices.Contains(schema.Required, name) {
+					continue
+				}
+				v.validate(propSchema, value, propPointer)
+			}
+		}
+		for pattern, patternSchema := range schema.PatternProperties {
+			if v.match(pattern, name, propPointer) {
+				known = true
+				v.validate(patternSchema, value, propPointer)
+			}
+		}
+
+		if !known && schema.AdditionalProperties != nil {
+			if isBoolSchema(schema.AdditionalProperties, false) {
+				v.addf(propPointer, "unknown property is not allowed")
+			} else {
+				v.validate(schema.AdditionalProperties, value, propPointer)
+			}
+		}
+	}
+}
+
+// validateArray checks the array keywords: items, prefixItems, size bounds and uniqueness.
+func (v *validator) validateArray(schema *jsonschema.Schema, arr []interface{}, pointer string) {
+	if schema.MinItems != nil && uint64(len(arr)) < *schema.MinItems {
+		v.addf(pointer, "array must have at least %d items", *schema.MinItems)
+	}
+	if schema.MaxItems != nil && uint64(len(arr)) > *schema.MaxItems {
+		v.addf(pointer, "array must have at most %d items", *schema.MaxItems)
+	}
+	for i, item := range arr {
+		itemPointer := childPointer(pointer, strconv.Itoa(i))
+		if i < len(schema.PrefixItems) {
+			v.validate(schema.PrefixItems[i], item, itemPointer)
+		} else if schema.Items != nil {
+			v.validate(schema.Items, item, itemPointer)
+		}
+	}
+	if schema.UniqueItems {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.addf(childPointer(pointer, strconv.Itoa(j)), "duplicate of item %d, items must be unique", i)
+				}
+			}
+		}
+	}
+}
+
+// validateString checks the string keywords: length bounds and pattern.
+func (v *validator) validateString(schema *jsonschema.Schema, s string, pointer string) {
+	length := uint64(utf8.RuneCountInString(s))
+	if schema.MinLength != nil && length < *schema.MinLength {
+		v.addf(pointer, "string must be at least %d characters long", *schema.MinLength)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.addf(pointer, "string must be at most %d characters long", *schema.MaxLength)
+	}
+	if schema.Pattern != "" && !v.match(schema.Pattern, s, pointer) {
+		v.addf(pointer, "string must match pattern %q", schema.Pattern)
+	}
+}
+
+// match reports whether s matches pattern. Patterns missing from the compiled patterns, of
+// schemas changed after compilePatterns, are compiled on demand; invalid ones never match
+// and are reported as a violation.
+func (v *validator) match(pattern, s, pointer string) bool {
+	re, ok := v.patterns[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			v.addf(pointer, "input schema pattern %q is not a valid regular expression", pattern)
+			return false
+		}
+	}
+	return re.MatchString(s)
+}
+
+// validateNumber checks the numeric keywords: bounds and multipleOf.
+func (v *validator) validateNumber(schema *jsonschema.Schema, n json.Number, pointer string) {
+	value, ok := new(big.Float).SetString(n.String())
+	if !ok {
+		return
+	}
+	check := func(bound json.Number, fails func(cmp int) bool, format string) {
+		if bound == "" {
+			return
+		}
+		limit, ok := new(big.Float).SetString(bound.String())
+		if ok && fails(value.Cmp(limit)) {
+			v.addf(pointer, format, bound)
+		}
+	}
+	check(schema.Minimum, func(c int) bool { return c < 0 }, "value must be >= %s")
+	check(schema.Maximum, func(c int) bool { return c > 0 }, "value must be <= %s")
+	check(schema.ExclusiveMinimum, func(c int) bool { return c <= 0 }, "value must be > %s")
+	check(schema.ExclusiveMaximum, func(c int) bool { return c >= 0 }, "value must be < %s")
+
+	if schema.MultipleOf != "" {
+		divisor, err1 := schema.MultipleOf.Float64()
+		f, err2 := n.Float64()
+		if err1 == nil && err2 == nil && divisor != 0 {
+			q := f / divisor
+			if math.Abs(q-math.Round(q)) > 1e-9 {
+				v.addf(pointer, "value must be a multiple of %s", schema.MultipleOf)
+			}
+		}
+	}
+}
+
+// validateCombinators checks allOf, anyOf, oneOf, not and if/then/else.
+func (v *validator) validateCombinators(schema *jsonschema.Schema, value interface{}, pointer string) {
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, pointer)
+	}
+	if len(schema.AnyOf) > 0 {
+		matched := false
+		for _, sub := range schema.AnyOf {
+			if v.matches(sub, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.addf(pointer, "value does not match any of the allowed schemas (anyOf)")
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		v.validateOneOf(schema.OneOf, value, pointer)
+	}
+	if schema.Not != nil && v.matches(schema.Not, value) {
+		v.addf(pointer, "value matches a schema it must not match (not)")
+	}
+	if schema.If != nil {
+		if v.matches(schema.If, value) {
+			v.validate(schema.Then, value, pointer)
+		} else {
+			v.validate(schema.Else, value, pointer)
+		}
+	}
+}
+
+// validateOneOf requires exactly one variant to match. When none matches and a
+// single variant is the obvious candidate (all others fail on a const or enum
+// discriminator), that variant's violations are reported for precise feedback.
+func (v *validator) validateOneOf(variants []*jsonschema.Schema, value interface{}, pointer string) {
+	var matched int
+	var candidates []*validator
+	for _, sub := range variants {
+		probe := &validator{}
+		probe.validate(sub, value, pointer)
+		if len(probe.violations) == 0 {
+			matched++
+			continue
+		}
+		if !failsDiscriminator(sub, value) {
+			candidates = append(candidates, probe)
+		}
+	}
+	switch {
+	case matched == 1:
+	case matched > 1:
+		v.addf(pointer, "value matches more than one of the allowed schemas (oneOf)")
+	case len(candidates) == 1:
+		v.violations = append(v.violations, candidates[0].violations...)
+	default:
+		v.addf(pointer, "value does not match any of the allowed schemas (oneOf)")
+	}
+}
+
+// failsDiscriminator reports whether value fails one of the const or enum properties
+// of an object schema, meaning the schema is clearly not the intended variant.
+func failsDiscriminator(schema *jsonschema.Schema, value interface{}) bool {
+	obj, ok := value.(map[string]interface{})
+	if !ok || schema.Properties == nil {
+		return false
+	}
+	for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		prop := pair.Value
+		if prop == nil || (prop.Const == nil && len(prop.Enum) == 0) {
+			continue
+		}
+		actual, present := obj[pair.Key]
+		if !present {
+			continue
+		}
+		if prop.Const != nil && !jsonEqual(prop.Const, actual) {
+			return true
+		}
+		if len(prop.Enum) > 0 && !containsValue(prop.Enum, actual) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Helpers ---
+
+// isBoolSchema reports whether schema is the boolean schema `true` or `false`.
+func isBoolSchema(schema *jsonschema.Schema, b bool) bool {
+	if b {
+		return schema == jsonschema.TrueSchema || reflect.DeepEqual(*schema, *jsonschema.TrueSchema)
+	}
+	return schema == jsonschema.FalseSchema || reflect.DeepEqual(*schema, *jsonschema.FalseSchema)
+}
+
+// hasType reports whether value matches the JSON schema type name.
+func hasType(value interface{}, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, ok := new(big.Float).SetString(n.String())
+		return ok && f.IsInt()
+	default:
+		return true // Unknown types are not enforced
+	}
+}
+
+// jsonTypeOf returns the JSON type name of a decoded value.
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// jsonEqual compares two values by their canonical JSON encoding, so that schema
+// literals (decoded without UseNumber) compare equal to argument values.
+func jsonEqual(a, b interface{}) bool {
+	ca, errA := canonicalJSON(a)
+	cb, errB := canonicalJSON(b)
+	return errA == nil && errB == nil && ca == cb
+}
+
+// canonicalJSON encodes a value and re-decodes numbers so 1, 1.0 and "1" (as json.Number) agree.
+func canonicalJSON(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(decoded)
+	return string(out), err
+}
+
+// containsValue reports whether value equals one of the candidates.
+func containsValue(candidates []any, value interface{}) bool {
+	for _, c := range candidates {
+		if jsonEqual(c, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnum renders a list of allowed values as JSON for messages.
+func formatEnum(values []any) string {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Sprint(values)
+	}
+	if len(values) == 1 {
+		return string(raw[1 : len(raw)-1])
+	}
+	return string(raw)
+}
+
+// childPointer appends an escaped reference token to a JSON pointer.
+func childPointer(pointer, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return pointer + "/" + token
+}
+
+// sortedKeys returns the keys of obj in lexical order for deterministic reports.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}